@@ -0,0 +1,5 @@
+-- AlterTable
+ALTER TABLE "alerts" ADD COLUMN     "incidentId" TEXT;
+
+-- CreateIndex
+CREATE UNIQUE INDEX "alerts_incidentId_key" ON "alerts"("incidentId");
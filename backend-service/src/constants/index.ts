@@ -59,6 +59,7 @@ export const RESPONSE_SUCCESS_MESSAGES = {
   ALERTS_RETRIEVED: "Alerts retrieved successfully",
   ALERT_RETRIEVED: "Alert retrieved successfully",
   ALERT_STATS_RETRIEVED: "Camera alert stats retrieved successfully",
+  INCIDENT_EVENT_APPLIED: "Incident event applied successfully",
 };
 
 // ----------------------------------------------------------------------
@@ -85,4 +86,5 @@ export const RESPONSE_ERROR_MESSAGES = {
   ALERT_RETRIEVE_FAILED: "Failed to retrieve alert",
   ALERT_NOT_FOUND: "Alert not found",
   ALERT_STATS_RETRIEVE_FAILED: "Failed to retrieve camera alert stats",
+  INCIDENT_EVENT_FAILED: "Failed to apply incident event",
 };
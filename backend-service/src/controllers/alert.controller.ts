@@ -12,7 +12,11 @@ import {
   RESPONSE_SUCCESS_MESSAGES,
   STATUS_CODES,
 } from "@/constants/index.js";
-import { AlertsQueryParams, CreateAlertRequest } from "@/dtos/alert.dto.js";
+import {
+  AlertsQueryParams,
+  CreateAlertRequest,
+  IncidentEventRequest,
+} from "@/dtos/alert.dto.js";
 import { createAlertService } from "@/services/alert.service.js";
 import { AlertNotification } from "@/types/websocket.js";
 import { websocketService } from "@/libs/websocket.lib.js";
@@ -76,6 +80,34 @@ export const createAlertController = (prisma: PrismaClient) => {
     }
   };
 
+  /**
+   * Apply an incident update or close to the alert of the incident.
+   *
+   * @route POST /api/alerts/incident-events
+   */
+  const applyIncidentEvent = async (c: Context) => {
+    try {
+      const eventData = c.get("validatedData") as IncidentEventRequest;
+
+      const alert = await alertService.applyIncidentEvent(eventData);
+
+      return successResponse(
+        c,
+        STATUS_CODES.OK,
+        RESPONSE_MESSAGES.SUCCESS,
+        RESPONSE_SUCCESS_MESSAGES.INCIDENT_EVENT_APPLIED,
+        alert
+      );
+    } catch (error) {
+      return errorResponse(
+        c,
+        STATUS_CODES.BAD_REQUEST,
+        RESPONSE_ERROR_MESSAGES.INCIDENT_EVENT_FAILED,
+        error
+      );
+    }
+  };
+
   /**
    * Retrieve the list of alerts.
    *
@@ -205,6 +237,7 @@ export const createAlertController = (prisma: PrismaClient) => {
   /* Return all controller functions */
   return {
     createAlert,
+    applyIncidentEvent,
     getAlerts,
     getAlertById,
     getRecentAlertsByCamera,
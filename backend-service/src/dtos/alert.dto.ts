@@ -1,4 +1,5 @@
 export interface CreateAlertRequest {
+  incidentId?: string;
   cameraId: string;
   faceCount: number;
   confidence: number;
@@ -8,6 +9,22 @@ export interface CreateAlertRequest {
 
 // ----------------------------------------------------------------------
 
+export interface IncidentEventRequest {
+  event: "opened" | "updated" | "closed";
+  incidentId: string;
+  incident: Record<string, any>;
+  cameraId: string;
+  faceCount: number;
+  confidence: number;
+  snapshotUrl?: string | null;
+  annotatedSnapshotUrl?: string | null;
+  clipUrl?: string | null;
+  clipDurationSeconds?: number;
+  metadata?: Record<string, any>;
+}
+
+// ----------------------------------------------------------------------
+
 export interface AlertResponse {
   id: string;
   cameraId: string;
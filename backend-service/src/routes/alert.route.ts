@@ -13,6 +13,7 @@ import {
 import {
   alertsQuerySchema,
   createAlertSchema,
+  incidentEventSchema,
 } from "@/validators/alert.validator.js";
 import { createAlertController } from "@/controllers/alert.controller.js";
 
@@ -41,6 +42,14 @@ export const createAlertRoutes = (prisma: PrismaClient) => {
     alertController.createAlert
   );
 
+  // Worker service endpoint for incident updates and closes
+  alertRoutes.post(
+    "/incident-events",
+    authMiddleware,
+    validateBody(incidentEventSchema),
+    alertController.applyIncidentEvent
+  );
+
   // User endpoints (authenticated via JWT)
   alertRoutes.get(
     "/get-alerts",
@@ -6,6 +6,7 @@ import {
   AlertResponse,
   AlertsQueryParams,
   CreateAlertRequest,
+  IncidentEventRequest,
 } from "@/dtos/alert.dto.js";
 import { logger } from "@/libs/logger.lib.js";
 import { PaginatedResponse } from "@/types/index.js";
@@ -21,6 +22,36 @@ import { RESPONSE_ERROR_MESSAGES } from "@/constants/index.js";
  */
 
 export const createAlertService = (prisma: PrismaClient) => {
+  /* Camera fields returned with every alert */
+  const alertCameraInclude = {
+    camera: {
+      select: {
+        id: true,
+        name: true,
+        location: true,
+      },
+    },
+  } as const;
+
+  /**
+   * Map an alert with its camera to the alert response.
+   *
+   * @param {Prisma.AlertGetPayload} alert - Alert with its camera
+   * @returns {AlertResponse} - Alert response
+   */
+  const toAlertResponse = (
+    alert: Prisma.AlertGetPayload<{ include: typeof alertCameraInclude }>
+  ): AlertResponse => ({
+    id: alert.id,
+    cameraId: alert.cameraId,
+    faceCount: alert.faceCount,
+    confidence: alert.confidence,
+    snapshotUrl: alert.snapshotUrl,
+    metadata: alert.metadata as Record<string, any> | null,
+    createdAt: alert.createdAt,
+    camera: alert.camera,
+  });
+
   /**
    * Create a new alert.
    *
@@ -39,37 +70,97 @@ export const createAlertService = (prisma: PrismaClient) => {
       throw new Error(RESPONSE_ERROR_MESSAGES.CAMERA_NOT_FOUND);
     }
 
+    // A retried incident alert returns the alert already created for the incident
+    if (alertData.incidentId) {
+      const existing = await prisma.alert.findUnique({
+        where: { incidentId: alertData.incidentId },
+        include: alertCameraInclude,
+      });
+
+      if (existing) {
+        return toAlertResponse(existing);
+      }
+    }
+
     const alert = await prisma.alert.create({
       data: {
+        incidentId: alertData.incidentId || null,
         cameraId: alertData.cameraId,
         faceCount: alertData.faceCount,
         confidence: alertData.confidence,
         snapshotUrl: alertData.snapshotUrl || null,
         metadata: (alertData.metadata as Prisma.JsonObject) || null,
       },
-      include: {
-        camera: {
-          select: {
-            id: true,
-            name: true,
-            location: true,
-          },
-        },
-      },
+      include: alertCameraInclude,
     });
 
     logger.info(`Alert created: ${alert.id} for camera ${alertData.cameraId}`);
 
-    return {
-      id: alert.id,
-      cameraId: alert.cameraId,
-      faceCount: alert.faceCount,
-      confidence: alert.confidence,
-      snapshotUrl: alert.snapshotUrl,
-      metadata: alert.metadata as Record<string, any> | null,
-      createdAt: alert.createdAt,
-      camera: alert.camera,
-    };
+    return toAlertResponse(alert);
+  };
+
+  /**
+   * Apply an incident event to the alert of its incident. The alert takes the latest
+   * face count, confidence and snapshot, and its metadata keeps the incident summary.
+   * An incident whose alert is missing gets one, so its events are never lost.
+   *
+   * @param {IncidentEventRequest} eventData - Incident event to apply
+   * @returns {Promise<AlertResponse>} - Updated alert response
+   * @throws {Error} - If camera is not found
+   */
+  const applyIncidentEvent = async (
+    eventData: IncidentEventRequest
+  ): Promise<AlertResponse> => {
+    const camera = await prisma.camera.findUnique({
+      where: { id: eventData.cameraId },
+    });
+
+    if (!camera) {
+      throw new Error(RESPONSE_ERROR_MESSAGES.CAMERA_NOT_FOUND);
+    }
+
+    const existing = await prisma.alert.findUnique({
+      where: { incidentId: eventData.incidentId },
+    });
+
+    const metadata = {
+      ...((existing?.metadata as Prisma.JsonObject) || {}),
+      ...(eventData.metadata || {}),
+      event: eventData.event,
+      incident: eventData.incident,
+      ...(eventData.annotatedSnapshotUrl && {
+        annotatedSnapshotUrl: eventData.annotatedSnapshotUrl,
+      }),
+      ...(eventData.clipUrl && {
+        clipUrl: eventData.clipUrl,
+        clipDurationSeconds: eventData.clipDurationSeconds,
+      }),
+    } as Prisma.JsonObject;
+
+    const alert = await prisma.alert.upsert({
+      where: { incidentId: eventData.incidentId },
+      create: {
+        incidentId: eventData.incidentId,
+        cameraId: eventData.cameraId,
+        faceCount: eventData.faceCount,
+        confidence: eventData.confidence,
+        snapshotUrl: eventData.snapshotUrl || null,
+        metadata,
+      },
+      update: {
+        faceCount: eventData.faceCount,
+        confidence: eventData.confidence,
+        ...(eventData.snapshotUrl && { snapshotUrl: eventData.snapshotUrl }),
+        metadata,
+      },
+      include: alertCameraInclude,
+    });
+
+    logger.info(
+      `Incident ${eventData.incidentId} ${eventData.event} for alert ${alert.id}`
+    );
+
+    return toAlertResponse(alert);
   };
 
   /**
@@ -300,6 +391,7 @@ export const createAlertService = (prisma: PrismaClient) => {
 
   return {
     createAlert,
+    applyIncidentEvent,
     getAlerts,
     getAlertById,
     getRecentAlertsByCamera,
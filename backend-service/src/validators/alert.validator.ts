@@ -4,6 +4,7 @@ import { z } from "zod";
 // ----------------------------------------------------------------------
 
 export const createAlertSchema = z.object({
+  incidentId: z.string().min(1).optional(),
   cameraId: z.string().min(1, "Camera ID is required"),
   faceCount: z.number().int().min(0),
   confidence: z.number().min(0).max(1),
@@ -13,6 +14,22 @@ export const createAlertSchema = z.object({
 
 // ----------------------------------------------------------------------
 
+export const incidentEventSchema = z.object({
+  event: z.enum(["opened", "updated", "closed"]),
+  incidentId: z.string().min(1, "Incident ID is required"),
+  incident: z.record(z.string(), z.any()),
+  cameraId: z.string().min(1, "Camera ID is required"),
+  faceCount: z.number().int().min(0),
+  confidence: z.number().min(0).max(1),
+  snapshotUrl: z.string().url().nullable().optional(),
+  annotatedSnapshotUrl: z.string().url().nullable().optional(),
+  clipUrl: z.string().url().nullable().optional(),
+  clipDurationSeconds: z.number().min(0).optional(),
+  metadata: z.record(z.string(), z.any()).optional(),
+});
+
+// ----------------------------------------------------------------------
+
 export const alertsQuerySchema = z.object({
   cameraId: z.string().optional(),
   startDate: z.string().datetime().optional(),
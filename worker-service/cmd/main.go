@@ -9,6 +9,9 @@ import (
 	"os/signal"
 	"syscall"
 
+	// Embedded time zone database for overlay time zones in images without one
+	_ "time/tzdata"
+
 	"worker-service/internal/config"
 	"worker-service/internal/handlers"
 	"worker-service/internal/middleware"
@@ -48,8 +51,24 @@ func main() {
 
 	// Initialize services
 	mediamtxClient := services.NewMediaMTXClient(cfg.MediaMTXAPIURL)
-	alertService := services.NewAlertService(cfg)
-	streamManager := services.NewStreamManager(cfg, mediamtxClient, alertService)
+
+	var eventStore *services.EventStore
+	if cfg.EventStoreEnabled {
+		store, err := services.NewEventStore(cfg)
+		if err != nil {
+			logger.Warnf("⚠️ Event store disabled: %v", err)
+		} else {
+			store.Start()
+			eventStore = store
+			logger.Infof("✅ Event store opened at %s", cfg.EventStorePath)
+		}
+	}
+
+	alertService := services.NewAlertService(cfg, eventStore)
+	recordingManager := services.NewRecordingManager(cfg)
+	recordingManager.Start()
+
+	streamManager := services.NewStreamManager(cfg, mediamtxClient, alertService, recordingManager, eventStore)
 
 	// ----------------------------------------------------------------------
 
@@ -71,7 +90,7 @@ func main() {
 	server := setupServer(cfg, cameraHandler)
 
 	// Setup graceful shutdown
-	setupGracefulShutdown(server, streamManager, logger)
+	setupGracefulShutdown(server, streamManager, alertService, recordingManager, eventStore, logger)
 
 	// Start server
 	addr := fmt.Sprintf(":%d", cfg.Port)
@@ -95,6 +114,7 @@ func setupServer(cfg *config.Config, cameraHandler *handlers.CameraHandler) *gin
 	// Public routes
 	engine.GET("/health", cameraHandler.HealthCheck)
 	engine.GET("/api/v1/health", cameraHandler.HealthCheck)
+	engine.GET("/snapshots/*key", cameraHandler.GetStoredSnapshot)
 
 	// ----------------------------------------------------------------------
 
@@ -104,9 +124,26 @@ func setupServer(cfg *config.Config, cameraHandler *handlers.CameraHandler) *gin
 	{
 		api.POST("/cameras/start-stream", cameraHandler.StartStream)
 		api.POST("/cameras/stop-stream", cameraHandler.StopStream)
+		api.POST("/cameras/probe", cameraHandler.ProbeStream)
 		api.GET("/cameras/:id/status", cameraHandler.GetStreamStatus)
 		api.POST("/cameras/:id/toggle-face-detection", cameraHandler.ToggleFaceDetection)
 		api.POST("/cameras/:id/update-fps", cameraHandler.UpdateFPS)
+		api.PUT("/cameras/:id/alert-rules", cameraHandler.UpdateAlertRules)
+		api.PUT("/cameras/:id/alert-sinks", cameraHandler.UpdateAlertSinks)
+		api.PUT("/cameras/:id/zones", cameraHandler.UpdateZones)
+		api.PUT("/cameras/:id/privacy-masks", cameraHandler.UpdatePrivacyMasks)
+		api.PUT("/cameras/:id/anonymization", cameraHandler.UpdateAnonymization)
+		api.PUT("/cameras/:id/detection-settings", cameraHandler.UpdateDetectionSettings)
+		api.GET("/cameras/:id/overlay", cameraHandler.GetOverlay)
+		api.PUT("/cameras/:id/overlay", cameraHandler.UpdateOverlay)
+		api.GET("/cameras/:id/snapshot", cameraHandler.GetSnapshot)
+		api.GET("/cameras/:id/mjpeg", cameraHandler.StreamMJPEG)
+		api.GET("/cameras/:id/recordings", cameraHandler.ListRecordings)
+		api.GET("/cameras/:id/detections/stream", cameraHandler.StreamDetections)
+		api.GET("/detections/stream", cameraHandler.StreamAllDetections)
+		api.GET("/events", cameraHandler.ListEvents)
+		api.GET("/cameras/:id/ptz", cameraHandler.GetPTZPresets)
+		api.POST("/cameras/:id/ptz", cameraHandler.ControlPTZ)
 	}
 
 	return engine
@@ -115,7 +152,7 @@ func setupServer(cfg *config.Config, cameraHandler *handlers.CameraHandler) *gin
 // ----------------------------------------------------------------------
 
 // setupGracefulShutdown handles OS signals for graceful shutdown
-func setupGracefulShutdown(server *gin.Engine, streamManager *services.StreamManager, logger *logrus.Logger) {
+func setupGracefulShutdown(server *gin.Engine, streamManager *services.StreamManager, alertService *services.AlertService, recordingManager *services.RecordingManager, eventStore *services.EventStore, logger *logrus.Logger) {
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
@@ -135,6 +172,12 @@ func setupGracefulShutdown(server *gin.Engine, streamManager *services.StreamMan
 			}
 		}
 
+		alertService.Close()
+		recordingManager.Stop()
+		if eventStore != nil {
+			eventStore.Close()
+		}
+
 		logger.Infof("✅ All streams stopped (%d total). Exiting...", stoppedCount)
 		os.Exit(0)
 	}()
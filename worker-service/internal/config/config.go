@@ -7,12 +7,28 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"worker-service/internal/utils"
 
 	"github.com/joho/godotenv"
 )
 
 // ----------------------------------------------------------------------
 
+// Default overlay fonts: the Noto fonts installed in the worker image, covering non-Latin scripts.
+// Fonts missing on the host are skipped, leaving the embedded Go font.
+const defaultOverlayFontPath = "/usr/share/fonts/truetype/noto/NotoSans-Regular.ttf"
+
+var defaultOverlayFallbackFontPaths = []string{
+	"/usr/share/fonts/opentype/noto/NotoSansCJK-Regular.ttc",
+	"/usr/share/fonts/truetype/noto/NotoSansArabic-Regular.ttf",
+	"/usr/share/fonts/truetype/noto/NotoSansDevanagari-Regular.ttf",
+	"/usr/share/fonts/truetype/noto/NotoSansThai-Regular.ttf",
+	"/usr/share/fonts/truetype/noto/NotoSansHebrew-Regular.ttf",
+}
+
+// ----------------------------------------------------------------------
+
 /* Config holds all configuration for the worker service */
 type Config struct {
 	// Server
@@ -34,10 +50,78 @@ type Config struct {
 
 	// Stream processing
 	OptimalStreamCapacity int
+	MaxStreamCapacity     int
+
+	// MJPEG preview
+	MJPEGMaxFPS     int
+	MJPEGMaxWidth   int
+	MJPEGMaxClients int // per camera, 0 for unlimited
+
+	// Alert clips
+	AlertClipEnabled     bool
+	AlertClipPreSeconds  int
+	AlertClipPostSeconds int
+	AlertClipBufferDir   string
+
+	// Alert incidents
+	AlertIncidentQuietSeconds int
+
+	// Alert outbox
+	AlertOutboxDir               string
+	AlertOutboxMaxAttempts       int
+	AlertOutboxMaxBackoffSeconds int
+
+	// Alert sinks
+	AlertSinksFile string
+
+	// Alert signing (comma-separated active secrets, all used to sign)
+	AlertSigningSecrets []string
+
+	// Alert snapshots
+	AlertAnnotatedSnapshots     bool
+	AlertMaxFaceCrops           int
+	AlertFaceCropPaddingPercent int
+
+	// Recording
+	RecordingDir            string
+	RecordingSegmentSeconds int
+	RecordingRetentionHours int
+	RecordingMaxDiskMB      int
+
+	// Event store
+	EventStoreEnabled   bool
+	EventStorePath      string
+	EventRetentionHours int
+
+	// Anonymization
+	AllowUnredactedAlerts bool
 
 	// Face detection
 	FaceDetectionModelPath string
 
+	// Overlay fonts (fallbacks are comma-separated, tried in order for missing glyphs)
+	OverlayFontPath          string
+	OverlayFallbackFontPaths []string
+
+	// Overlay logos (PNG files selectable per camera)
+	OverlayLogoDir string
+
+	// Snapshot storage
+	SnapshotStore            string
+	SnapshotSignedURLs       bool
+	SnapshotURLExpirySeconds int
+	SnapshotSigningKey       string
+	SnapshotLocalDir         string
+	SnapshotPublicBaseURL    string
+
+	// S3-compatible snapshot storage
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3ForcePathStyle  bool
+
 	// Cloudinary
 	CloudinaryCloudName string
 	CloudinaryAPIKey    string
@@ -52,23 +136,63 @@ func LoadConfig() (*Config, error) {
 	_ = godotenv.Load()
 
 	config := &Config{
-		Port:                   getEnvInt("WORKER_SERVICE_PORT", 5000),
-		GinMode:                getEnvString("GIN_MODE", "debug"),
-		LogLevel:               getEnvString("LOG_LEVEL", "info"),
-		BackendServiceURL:      getEnvString("BACKEND_SERVICE_URL", "http://visionguard-backend:3000"),
-		BackendWorkerAPIKey:    getEnvString("BACKEND_WORKER_API_KEY", ""),
-		MediaMTXHost:           getEnvString("MEDIAMTX_HOST", "visionguard-mediamtx"),
-		MediaMTXRTSPPort:       getEnvInt("MEDIAMTX_RTSP_PORT", 8554),
-		MediaMTXHLSPort:        getEnvInt("MEDIAMTX_HLS_PORT", 8888),
-		MediaMTXWebRTCPort:     getEnvInt("MEDIAMTX_WEBRTC_PORT", 8889),
-		MediaMTXRTMPPort:       getEnvInt("MEDIAMTX_RTMP_PORT", 1935),
-		MediaMTXAPIURL:         getEnvString("MEDIAMTX_API_URL", "http://visionguard-mediamtx:9997"),
-		OptimalStreamCapacity:  getEnvInt("OPTIMAL_STREAM_CAPACITY", 4),
-		FaceDetectionModelPath: getEnvString("FACE_DETECTION_MODEL_PATH", "/app/models"),
-		CloudinaryCloudName:    getEnvString("CLOUDINARY_CLOUD_NAME", ""),
-		CloudinaryAPIKey:       getEnvString("CLOUDINARY_API_KEY", ""),
-		CloudinaryAPISecret:    getEnvString("CLOUDINARY_API_SECRET", ""),
-		CloudinaryFolder:       getEnvString("CLOUDINARY_FOLDER", "visionguard/snapshots"),
+		Port:                         getEnvInt("WORKER_SERVICE_PORT", 5000),
+		GinMode:                      getEnvString("GIN_MODE", "debug"),
+		LogLevel:                     getEnvString("LOG_LEVEL", "info"),
+		BackendServiceURL:            getEnvString("BACKEND_SERVICE_URL", "http://visionguard-backend:3000"),
+		BackendWorkerAPIKey:          getEnvString("BACKEND_WORKER_API_KEY", ""),
+		MediaMTXHost:                 getEnvString("MEDIAMTX_HOST", "visionguard-mediamtx"),
+		MediaMTXRTSPPort:             getEnvInt("MEDIAMTX_RTSP_PORT", 8554),
+		MediaMTXHLSPort:              getEnvInt("MEDIAMTX_HLS_PORT", 8888),
+		MediaMTXWebRTCPort:           getEnvInt("MEDIAMTX_WEBRTC_PORT", 8889),
+		MediaMTXRTMPPort:             getEnvInt("MEDIAMTX_RTMP_PORT", 1935),
+		MediaMTXAPIURL:               getEnvString("MEDIAMTX_API_URL", "http://visionguard-mediamtx:9997"),
+		OptimalStreamCapacity:        getEnvInt("OPTIMAL_STREAM_CAPACITY", 4),
+		MaxStreamCapacity:            getEnvInt("MAX_STREAM_CAPACITY", 0),
+		MJPEGMaxFPS:                  getEnvInt("MJPEG_MAX_FPS", 5),
+		MJPEGMaxWidth:                getEnvInt("MJPEG_MAX_WIDTH", 640),
+		MJPEGMaxClients:              getEnvInt("MJPEG_MAX_CLIENTS", 4),
+		AlertClipEnabled:             getEnvBool("ALERT_CLIP_ENABLED", false),
+		AlertClipPreSeconds:          getEnvInt("ALERT_CLIP_PRE_SECONDS", 10),
+		AlertClipPostSeconds:         getEnvInt("ALERT_CLIP_POST_SECONDS", 5),
+		AlertClipBufferDir:           getEnvString("ALERT_CLIP_BUFFER_DIR", "/tmp/visionguard/clips"),
+		AlertIncidentQuietSeconds:    getEnvInt("ALERT_INCIDENT_QUIET_SECONDS", 15),
+		AlertOutboxDir:               getEnvString("ALERT_OUTBOX_DIR", "/tmp/visionguard/outbox"),
+		AlertOutboxMaxAttempts:       getEnvInt("ALERT_OUTBOX_MAX_ATTEMPTS", 10),
+		AlertOutboxMaxBackoffSeconds: getEnvInt("ALERT_OUTBOX_MAX_BACKOFF_SECONDS", 300),
+		AlertSinksFile:               getEnvString("ALERT_SINKS_FILE", ""),
+		AlertSigningSecrets:          getEnvList("ALERT_SIGNING_SECRETS", nil),
+		AlertAnnotatedSnapshots:      getEnvBool("ALERT_ANNOTATED_SNAPSHOTS", false),
+		AlertMaxFaceCrops:            getEnvInt("ALERT_MAX_FACE_CROPS", 10),
+		AlertFaceCropPaddingPercent:  getEnvInt("ALERT_FACE_CROP_PADDING_PERCENT", 25),
+		RecordingDir:                 getEnvString("RECORDING_DIR", "/tmp/visionguard/recordings"),
+		RecordingSegmentSeconds:      getEnvInt("RECORDING_SEGMENT_SECONDS", 60),
+		RecordingRetentionHours:      getEnvInt("RECORDING_RETENTION_HOURS", 72),
+		RecordingMaxDiskMB:           getEnvInt("RECORDING_MAX_DISK_MB", 0),
+		EventStoreEnabled:            getEnvBool("EVENT_STORE_ENABLED", true),
+		EventStorePath:               getEnvString("EVENT_STORE_PATH", "/tmp/visionguard/events.db"),
+		EventRetentionHours:          getEnvInt("EVENT_RETENTION_HOURS", 168),
+		AllowUnredactedAlerts:        getEnvBool("ANONYMIZATION_ALLOW_UNREDACTED_ALERTS", false),
+		FaceDetectionModelPath:       getEnvString("FACE_DETECTION_MODEL_PATH", "/app/models"),
+		OverlayFontPath:              getEnvString("OVERLAY_FONT_PATH", defaultOverlayFontPath),
+		OverlayFallbackFontPaths:     getEnvList("OVERLAY_FALLBACK_FONT_PATHS", defaultOverlayFallbackFontPaths),
+		OverlayLogoDir:               getEnvString("OVERLAY_LOGO_DIR", ""),
+		SnapshotStore:                getEnvString("SNAPSHOT_STORE", "cloudinary"),
+		SnapshotSignedURLs:           getEnvBool("SNAPSHOT_SIGNED_URLS", false),
+		SnapshotURLExpirySeconds:     getEnvInt("SNAPSHOT_URL_EXPIRY_SECONDS", 0),
+		SnapshotSigningKey:           getEnvString("SNAPSHOT_SIGNING_KEY", ""),
+		SnapshotLocalDir:             getEnvString("SNAPSHOT_LOCAL_DIR", "/tmp/visionguard/snapshots"),
+		SnapshotPublicBaseURL:        getEnvString("SNAPSHOT_PUBLIC_BASE_URL", ""),
+		S3Endpoint:                   getEnvString("S3_ENDPOINT", ""),
+		S3Region:                     getEnvString("S3_REGION", "us-east-1"),
+		S3Bucket:                     getEnvString("S3_BUCKET", ""),
+		S3AccessKeyID:                getEnvString("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:            getEnvString("S3_SECRET_ACCESS_KEY", ""),
+		S3ForcePathStyle:             getEnvBool("S3_FORCE_PATH_STYLE", false),
+		CloudinaryCloudName:          getEnvString("CLOUDINARY_CLOUD_NAME", ""),
+		CloudinaryAPIKey:             getEnvString("CLOUDINARY_API_KEY", ""),
+		CloudinaryAPISecret:          getEnvString("CLOUDINARY_API_SECRET", ""),
+		CloudinaryFolder:             getEnvString("CLOUDINARY_FOLDER", "visionguard/snapshots"),
 	}
 
 	if err := config.Validate(); err != nil {
@@ -98,6 +222,77 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("OPTIMAL_STREAM_CAPACITY must be at least 1")
 	}
 
+	if c.MaxStreamCapacity < 0 {
+		return fmt.Errorf("MAX_STREAM_CAPACITY must not be negative")
+	}
+
+	if c.MJPEGMaxFPS < 1 || c.MJPEGMaxWidth < 16 || c.MJPEGMaxClients < 0 {
+		return fmt.Errorf("MJPEG_MAX_FPS must be at least 1, MJPEG_MAX_WIDTH at least 16 and MJPEG_MAX_CLIENTS not negative")
+	}
+
+	if c.AlertClipEnabled && (c.AlertClipPreSeconds < 0 || c.AlertClipPostSeconds < 0 || c.AlertClipPreSeconds+c.AlertClipPostSeconds == 0) {
+		return fmt.Errorf("ALERT_CLIP_PRE_SECONDS and ALERT_CLIP_POST_SECONDS must not be negative and must not both be 0")
+	}
+
+	if c.AlertIncidentQuietSeconds < 1 {
+		return fmt.Errorf("ALERT_INCIDENT_QUIET_SECONDS must be at least 1")
+	}
+
+	if c.AlertOutboxMaxAttempts < 1 || c.AlertOutboxMaxBackoffSeconds < 1 {
+		return fmt.Errorf("ALERT_OUTBOX_MAX_ATTEMPTS and ALERT_OUTBOX_MAX_BACKOFF_SECONDS must be at least 1")
+	}
+
+	for _, secret := range c.AlertSigningSecrets {
+		if len(secret) < utils.MinSigningSecretLength {
+			return fmt.Errorf("ALERT_SIGNING_SECRETS must be at least %d characters each", utils.MinSigningSecretLength)
+		}
+	}
+
+	if c.AlertMaxFaceCrops < 0 || c.AlertFaceCropPaddingPercent < 0 || c.AlertFaceCropPaddingPercent > 100 {
+		return fmt.Errorf("ALERT_MAX_FACE_CROPS must not be negative and ALERT_FACE_CROP_PADDING_PERCENT must be between 0 and 100")
+	}
+
+	if c.RecordingSegmentSeconds < 1 || c.RecordingRetentionHours < 0 || c.RecordingMaxDiskMB < 0 {
+		return fmt.Errorf("RECORDING_SEGMENT_SECONDS must be at least 1 and RECORDING_RETENTION_HOURS, RECORDING_MAX_DISK_MB must not be negative")
+	}
+
+	if c.EventRetentionHours < 0 {
+		return fmt.Errorf("EVENT_RETENTION_HOURS must not be negative")
+	}
+
+	if err := c.validateSnapshotStore(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateSnapshotStore checks the settings of the selected snapshot store
+func (c *Config) validateSnapshotStore() error {
+	if c.SnapshotURLExpirySeconds < 0 {
+		return fmt.Errorf("SNAPSHOT_URL_EXPIRY_SECONDS must not be negative")
+	}
+
+	switch c.SnapshotStore {
+	case "cloudinary":
+	case "local":
+		if c.SnapshotPublicBaseURL == "" {
+			return fmt.Errorf("SNAPSHOT_PUBLIC_BASE_URL is required for the local snapshot store")
+		}
+		if c.SnapshotSignedURLs && c.SnapshotSigningKey == "" {
+			return fmt.Errorf("SNAPSHOT_SIGNING_KEY is required to sign local snapshot URLs")
+		}
+	case "s3":
+		if c.S3Bucket == "" || c.S3AccessKeyID == "" || c.S3SecretAccessKey == "" {
+			return fmt.Errorf("S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for the s3 snapshot store")
+		}
+		if c.SnapshotURLExpirySeconds > 7*24*60*60 {
+			return fmt.Errorf("SNAPSHOT_URL_EXPIRY_SECONDS must be at most 604800 for the s3 snapshot store")
+		}
+	default:
+		return fmt.Errorf("SNAPSHOT_STORE must be cloudinary, local or s3")
+	}
+
 	return nil
 }
 
@@ -119,3 +314,25 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvList(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
@@ -6,6 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strconv"
+	"time"
 	"worker-service/internal/models"
 	"worker-service/internal/services"
 	"worker-service/internal/utils"
@@ -15,6 +19,12 @@ import (
 
 // ----------------------------------------------------------------------
 
+const (
+	detectionKeepAliveInterval = 15 * time.Second
+)
+
+// ----------------------------------------------------------------------
+
 // CameraHandler handles camera-related endpoints
 type CameraHandler struct {
 	streamManager *services.StreamManager
@@ -52,7 +62,7 @@ func (h *CameraHandler) StartStream(c *gin.Context) {
 
 	if err != nil {
 		logger.Errorf("Failed to start stream for camera %s: %v", req.CameraID, err)
-		utils.ErrorBadRequest(c, err)
+		utils.ErrorFromService(c, err)
 		return
 	}
 
@@ -60,6 +70,27 @@ func (h *CameraHandler) StartStream(c *gin.Context) {
 	utils.SuccessOK(c, fmt.Sprintf("Stream started successfully for camera %s", req.Name), resp)
 }
 
+// ProbeStream probes a stream URL and returns its diagnostics
+func (h *CameraHandler) ProbeStream(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	var req models.ProbeStreamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid probe request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request payload: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.ProbeStream(&req)
+	if err != nil {
+		logger.Warnf("Stream probe failed: %v", err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Stream probed successfully", resp)
+}
+
 // StopStream stops a camera stream
 func (h *CameraHandler) StopStream(c *gin.Context) {
 	logger := utils.GetLogger()
@@ -77,7 +108,7 @@ func (h *CameraHandler) StopStream(c *gin.Context) {
 	resp, err := h.streamManager.StopStream(req.CameraID)
 	if err != nil {
 		logger.Errorf("Failed to stop stream for camera %s: %v", req.CameraID, err)
-		utils.ErrorNotFound(c, err)
+		utils.ErrorFromService(c, err)
 		return
 	}
 
@@ -99,7 +130,7 @@ func (h *CameraHandler) GetStreamStatus(c *gin.Context) {
 	resp, err := h.streamManager.GetStreamStatus(cameraID)
 	if err != nil {
 		logger.Warnf("Stream status error for camera %s: %v", cameraID, err)
-		utils.ErrorNotFound(c, err)
+		utils.ErrorFromService(c, err)
 		return
 	}
 
@@ -131,7 +162,7 @@ func (h *CameraHandler) ToggleFaceDetection(c *gin.Context) {
 		}
 
 		if err := h.streamManager.ToggleFaceDetection(cameraID, manualReq.Enabled); err != nil {
-			utils.ErrorNotFound(c, err)
+			utils.ErrorFromService(c, err)
 			return
 		}
 
@@ -156,7 +187,7 @@ func (h *CameraHandler) ToggleFaceDetection(c *gin.Context) {
 	}
 
 	if err := h.streamManager.ToggleFaceDetection(cameraID, enabled); err != nil {
-		utils.ErrorNotFound(c, err)
+		utils.ErrorFromService(c, err)
 		return
 	}
 
@@ -195,7 +226,7 @@ func (h *CameraHandler) UpdateFPS(c *gin.Context) {
 
 	if err := h.streamManager.UpdateFPS(cameraID, req.TargetFPS); err != nil {
 		logger.Errorf("Failed to update FPS for camera %s: %v", cameraID, err)
-		utils.ErrorNotFound(c, err)
+		utils.ErrorFromService(c, err)
 		return
 	}
 
@@ -206,3 +237,471 @@ func (h *CameraHandler) UpdateFPS(c *gin.Context) {
 
 	utils.SuccessOK(c, "FPS updated successfully", resp)
 }
+
+// UpdateAlertRules replaces the alert rules of a camera stream
+func (h *CameraHandler) UpdateAlertRules(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var req models.AlertRuleSet
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid alert rules request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.UpdateAlertRules(cameraID, &req)
+	if err != nil {
+		logger.Errorf("Failed to update alert rules for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Alert rules updated successfully", resp)
+}
+
+// UpdateAlertSinks replaces the alert sinks of a camera stream
+func (h *CameraHandler) UpdateAlertSinks(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var req models.UpdateAlertSinksRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid alert sinks request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.UpdateAlertSinks(cameraID, &req)
+	if err != nil {
+		logger.Errorf("Failed to update alert sinks for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Alert sinks updated successfully", resp)
+}
+
+// UpdateZones replaces the detection zones of a camera stream
+func (h *CameraHandler) UpdateZones(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var req models.UpdateZonesRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid zones request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.UpdateZones(cameraID, &req)
+	if err != nil {
+		logger.Errorf("Failed to update zones for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Detection zones updated successfully", resp)
+}
+
+// UpdatePrivacyMasks replaces the privacy masks of a camera stream
+func (h *CameraHandler) UpdatePrivacyMasks(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var req models.UpdatePrivacyMasksRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid privacy masks request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.UpdatePrivacyMasks(cameraID, &req)
+	if err != nil {
+		logger.Errorf("Failed to update privacy masks for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Privacy masks updated successfully", resp)
+}
+
+// UpdateAnonymization changes the face anonymization of a camera stream
+func (h *CameraHandler) UpdateAnonymization(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var req models.AnonymizationOptions
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid anonymization request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.UpdateAnonymization(cameraID, &req)
+	if err != nil {
+		logger.Errorf("Failed to update anonymization for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Face anonymization updated successfully", resp)
+}
+
+// UpdateDetectionSettings changes the face detection settings of a camera stream
+func (h *CameraHandler) UpdateDetectionSettings(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var req models.UpdateDetectionSettingsRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid detection settings request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.UpdateDetectionSettings(cameraID, &req)
+	if err != nil {
+		logger.Errorf("Failed to update detection settings for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Detection settings updated successfully", resp)
+}
+
+// GetOverlay returns the overlay configuration of a camera stream
+func (h *CameraHandler) GetOverlay(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	resp, err := h.streamManager.GetOverlay(cameraID)
+	if err != nil {
+		logger.Errorf("Failed to get overlay for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Overlay retrieved successfully", resp)
+}
+
+// UpdateOverlay replaces the overlay configuration of a camera stream
+func (h *CameraHandler) UpdateOverlay(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var req models.OverlayConfig
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid overlay request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request body: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.UpdateOverlay(cameraID, &req)
+	if err != nil {
+		logger.Errorf("Failed to update overlay for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Overlay updated successfully", resp)
+}
+
+// ControlPTZ sends a PTZ command to a camera stream
+func (h *CameraHandler) ControlPTZ(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var req models.PTZControlRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logger.Warnf("Invalid PTZ request: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid request payload: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.ControlPTZ(cameraID, &req)
+	if err != nil {
+		logger.Errorf("PTZ command failed for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "PTZ command executed successfully", resp)
+}
+
+// GetPTZPresets returns the PTZ presets of a camera stream
+func (h *CameraHandler) GetPTZPresets(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	resp, err := h.streamManager.GetPTZPresets(cameraID)
+	if err != nil {
+		logger.Errorf("Failed to get PTZ presets for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "PTZ presets retrieved successfully", resp)
+}
+
+// ListEvents returns a page of stored detection events matching the query filters
+func (h *CameraHandler) ListEvents(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	var query models.EventsQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		logger.Warnf("Invalid events query: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid query parameters: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.ListEvents(&query)
+	if err != nil {
+		logger.Errorf("Failed to list events: %v", err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Events retrieved successfully", resp)
+}
+
+// ListRecordings lists the recorded segments of a camera within an optional time range
+func (h *CameraHandler) ListRecordings(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var query models.RecordingsQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		logger.Warnf("Invalid recordings query: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid query parameters: %v", err))
+		return
+	}
+
+	resp, err := h.streamManager.ListRecordings(cameraID, &query)
+	if err != nil {
+		logger.Errorf("Failed to list recordings for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	utils.SuccessOK(c, "Recordings retrieved successfully", resp)
+}
+
+// GetSnapshot returns the latest frame of a camera stream as an image
+func (h *CameraHandler) GetSnapshot(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var query models.SnapshotQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		logger.Warnf("Invalid snapshot query: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid query parameters: %v", err))
+		return
+	}
+
+	snapshot, err := h.streamManager.GetSnapshot(cameraID, &query)
+	if err != nil {
+		logger.Warnf("Failed to get snapshot for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Captured-At", snapshot.CapturedAt.UTC().Format(time.RFC3339Nano))
+	c.Header("X-Frame-Width", strconv.Itoa(snapshot.Width))
+	c.Header("X-Frame-Height", strconv.Itoa(snapshot.Height))
+	c.Data(utils.StatusOK, snapshot.ContentType, snapshot.Data)
+}
+
+// GetStoredSnapshot serves an alert snapshot kept in the local snapshot store
+func (h *CameraHandler) GetStoredSnapshot(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	path, err := h.streamManager.OpenStoredSnapshot(c.Param("key"), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		logger.Warnf("Failed to serve stored snapshot %s: %v", c.Param("key"), err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.File(path)
+}
+
+// StreamMJPEG serves a multipart MJPEG preview of a camera stream
+func (h *CameraHandler) StreamMJPEG(c *gin.Context) {
+	logger := utils.GetLogger()
+
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	var query models.MJPEGQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		logger.Warnf("Invalid MJPEG query: %v", err)
+		utils.ErrorBadRequest(c, fmt.Errorf("invalid query parameters: %v", err))
+		return
+	}
+
+	stream, err := h.streamManager.OpenMJPEGStream(cameraID, &query)
+	if err != nil {
+		logger.Warnf("Failed to open MJPEG stream for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+	defer stream.Close()
+
+	writer := multipart.NewWriter(c.Writer)
+	c.Header("Content-Type", "multipart/x-mixed-replace; boundary="+writer.Boundary())
+	c.Header("Cache-Control", "no-store")
+	c.Status(utils.StatusOK)
+
+	ctx := c.Request.Context()
+	for {
+		frame, err := stream.NextFrame(ctx)
+		if err != nil {
+			logger.Debugf("MJPEG stream for camera %s ended: %v", cameraID, err)
+			return
+		}
+
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":   {"image/jpeg"},
+			"Content-Length": {strconv.Itoa(len(frame))},
+		})
+		if err != nil {
+			return
+		}
+
+		if _, err := part.Write(frame); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// StreamDetections pushes live detection metadata of a camera stream as server-sent events
+func (h *CameraHandler) StreamDetections(c *gin.Context) {
+	cameraID := c.Param("id")
+	if cameraID == "" {
+		utils.ErrorBadRequest(c, fmt.Errorf("camera ID is required"))
+		return
+	}
+
+	h.streamDetectionEvents(c, cameraID)
+}
+
+// StreamAllDetections pushes live detection metadata of every stream on the worker as server-sent events
+func (h *CameraHandler) StreamAllDetections(c *gin.Context) {
+	h.streamDetectionEvents(c, "")
+}
+
+// streamDetectionEvents writes detection events to the client until it disconnects or the feed ends
+func (h *CameraHandler) streamDetectionEvents(c *gin.Context, cameraID string) {
+	logger := utils.GetLogger()
+
+	sub, err := h.streamManager.SubscribeDetections(cameraID)
+	if err != nil {
+		logger.Warnf("Failed to subscribe to detections for camera %s: %v", cameraID, err)
+		utils.ErrorFromService(c, err)
+		return
+	}
+	defer h.streamManager.UnsubscribeDetections(sub)
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(detectionKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-sub.Events():
+			if !ok {
+				c.SSEvent("end", gin.H{"cameraId": cameraID})
+				return false
+			}
+			c.SSEvent("detection", event)
+			return true
+		case <-keepAlive.C:
+			c.SSEvent("ping", time.Now().UTC().Format(time.RFC3339))
+			return true
+		}
+	})
+}
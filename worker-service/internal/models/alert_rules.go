@@ -0,0 +1,44 @@
+package models
+
+// ----------------------------------------------------------------------
+
+// Alert severity levels, lowest first
+const (
+	AlertSeverityLow      = "low"
+	AlertSeverityMedium   = "medium"
+	AlertSeverityHigh     = "high"
+	AlertSeverityCritical = "critical"
+)
+
+// ----------------------------------------------------------------------
+
+// AlertRuleSet is the set of alert rules of a camera; an empty set alerts on every detection
+type AlertRuleSet struct {
+	Timezone string      `json:"timezone,omitempty"`
+	Rules    []AlertRule `json:"rules" binding:"dive"`
+}
+
+// AlertRule decides which detections raise an alert
+type AlertRule struct {
+	Name          string       `json:"name" binding:"required"`
+	MinFaceCount  int          `json:"minFaceCount" binding:"min=0"`
+	MinConfidence float32      `json:"minConfidence" binding:"min=0,max=1"`
+	MinFaceSize   int32        `json:"minFaceSize" binding:"min=0"`
+	DwellSeconds  float64      `json:"dwellSeconds" binding:"min=0"`
+	ActiveWindows []TimeWindow `json:"activeWindows,omitempty" binding:"dive"`
+	Days          []string     `json:"days,omitempty" binding:"dive,oneof=mon tue wed thu fri sat sun"`
+	Severity      string       `json:"severity,omitempty" binding:"omitempty,oneof=low medium high critical"`
+}
+
+// TimeWindow is a daily time range in HH:MM; a start after the end wraps past midnight
+// and belongs to the day it starts on
+type TimeWindow struct {
+	Start string `json:"start" binding:"required"`
+	End   string `json:"end" binding:"required"`
+}
+
+// AlertRulesResponse is the response for updating the alert rules of a camera
+type AlertRulesResponse struct {
+	CameraID string       `json:"cameraId"`
+	RuleSet  AlertRuleSet `json:"ruleSet"`
+}
@@ -0,0 +1,75 @@
+package models
+
+// ----------------------------------------------------------------------
+
+// Alert sink types; the backend sink is built in and always configured
+const (
+	AlertSinkTypeBackend = "backend"
+	AlertSinkTypeWebhook = "webhook"
+	AlertSinkTypeEmail   = "email"
+	AlertSinkTypeSyslog  = "syslog"
+)
+
+// ----------------------------------------------------------------------
+
+// AlertSinkConfig configures one alert destination; exactly the section matching Type is used.
+// Events limits the incident events delivered to the sink; empty means all of them.
+type AlertSinkConfig struct {
+	Name    string             `json:"name" binding:"required"`
+	Type    string             `json:"type" binding:"required,oneof=webhook email syslog"`
+	Events  []string           `json:"events,omitempty" binding:"dive,oneof=opened updated closed"`
+	Webhook *WebhookSinkConfig `json:"webhook,omitempty"`
+	Email   *EmailSinkConfig   `json:"email,omitempty"`
+	Syslog  *SyslogSinkConfig  `json:"syslog,omitempty"`
+}
+
+// WebhookSinkConfig posts alerts to an arbitrary HTTP endpoint.
+// BodyTemplate is a Go text/template over the alert; without it the backend JSON payload is sent.
+// SigningSecrets replace the worker's alert signing secrets for this endpoint.
+type WebhookSinkConfig struct {
+	URL            string            `json:"url" binding:"required,url"`
+	Method         string            `json:"method,omitempty" binding:"omitempty,oneof=POST PUT PATCH"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	BodyTemplate   string            `json:"bodyTemplate,omitempty"`
+	TimeoutSeconds int               `json:"timeoutSeconds,omitempty" binding:"omitempty,min=1,max=120"`
+	SigningSecrets []string          `json:"signingSecrets,omitempty" binding:"omitempty,dive,min=16"`
+}
+
+// EmailSinkConfig sends alerts by SMTP with the snapshot attached.
+// ImplicitTLS connects over TLS (usually port 465); otherwise STARTTLS is used when offered.
+type EmailSinkConfig struct {
+	Host            string   `json:"host" binding:"required"`
+	Port            int      `json:"port" binding:"required,min=1,max=65535"`
+	Username        string   `json:"username,omitempty"`
+	Password        string   `json:"password,omitempty"`
+	ImplicitTLS     bool     `json:"implicitTls,omitempty"`
+	From            string   `json:"from" binding:"required,email"`
+	To              []string `json:"to" binding:"required,min=1,dive,email"`
+	SubjectTemplate string   `json:"subjectTemplate,omitempty"`
+	BodyTemplate    string   `json:"bodyTemplate,omitempty"`
+}
+
+// SyslogSinkConfig sends alerts as RFC 5424 syslog messages
+type SyslogSinkConfig struct {
+	Network  string `json:"network,omitempty" binding:"omitempty,oneof=udp tcp"`
+	Address  string `json:"address" binding:"required,hostname_port"`
+	Facility string `json:"facility,omitempty"`
+	AppName  string `json:"appName,omitempty"`
+}
+
+// UpdateAlertSinksRequest is the request payload for replacing the alert sinks of a camera
+type UpdateAlertSinksRequest struct {
+	Sinks []AlertSinkConfig `json:"sinks" binding:"dive"`
+}
+
+// AlertSinksResponse is the response for updating the alert sinks of a camera
+type AlertSinksResponse struct {
+	CameraID string            `json:"cameraId"`
+	Sinks    []AlertSinkConfig `json:"sinks"`
+}
+
+// AlertSinkStats reports the outbox deliveries of one sink
+type AlertSinkStats struct {
+	Pending      int `json:"pending"`
+	DeadLettered int `json:"deadLettered"`
+}
@@ -0,0 +1,27 @@
+package models
+
+// ----------------------------------------------------------------------
+
+// Face anonymization modes
+const (
+	AnonymizationModeBlur     = "blur"
+	AnonymizationModePixelate = "pixelate"
+)
+
+// ----------------------------------------------------------------------
+
+// AnonymizationOptions configures the redaction of detected faces in the published stream.
+// Padding grows each face box by a fraction of its size on every side.
+// KeepUnredactedForAlerts sends alert snapshots without redaction where the worker permits it.
+type AnonymizationOptions struct {
+	Enabled                 bool     `json:"enabled"`
+	Mode                    string   `json:"mode,omitempty" binding:"omitempty,oneof=blur pixelate"`
+	Padding                 *float64 `json:"padding,omitempty" binding:"omitempty,min=0,max=1"`
+	KeepUnredactedForAlerts bool     `json:"keepUnredactedForAlerts"`
+}
+
+// AnonymizationResponse is the response for updating the face anonymization of a camera
+type AnonymizationResponse struct {
+	CameraID      string               `json:"cameraId"`
+	Anonymization AnonymizationOptions `json:"anonymization"`
+}
@@ -2,17 +2,123 @@ package models
 
 // ----------------------------------------------------------------------
 
-// FaceDetection represents a single detected face
+import "time"
+
+// ----------------------------------------------------------------------
+
+// FaceDetection represents a single detected face.
+// Tracked faces keep their ID ("track_<TrackID>") across frames for as long as the tracker follows them.
 type FaceDetection struct {
-	ID         string  `json:"id"`
-	X          int32   `json:"x"`
-	Y          int32   `json:"y"`
-	Width      int32   `json:"width"`
-	Height     int32   `json:"height"`
-	Confidence float32 `json:"confidence"`
+	ID              string   `json:"id"`
+	X               int32    `json:"x"`
+	Y               int32    `json:"y"`
+	Width           int32    `json:"width"`
+	Height          int32    `json:"height"`
+	Confidence      float32  `json:"confidence"`
+	Zones           []string `json:"zones,omitempty"`
+	TrackID         int64    `json:"trackId,omitempty"`
+	TrackState      string   `json:"trackState,omitempty"`
+	TrackAgeSeconds float64  `json:"trackAgeSeconds,omitempty"`
+}
+
+// Track states of a face detection
+const (
+	TrackStateNew     = "new"     // first frame of the track
+	TrackStateTracked = "tracked" // matched in the previous frame too
+	TrackStateFound   = "found"   // matched again after being lost
+)
+
+// TrackingStats summarizes the face tracks of a camera
+type TrackingStats struct {
+	ActiveTracks int   `json:"activeTracks"`
+	LostTracks   int   `json:"lostTracks"`
+	TotalTracks  int64 `json:"totalTracks"`
+}
+
+// DetectionEvent carries the detections of a single processed frame
+type DetectionEvent struct {
+	CameraID    string          `json:"cameraId"`
+	Sequence    int64           `json:"sequence"`
+	CapturedAt  time.Time       `json:"capturedAt"`
+	FrameWidth  int             `json:"frameWidth"`
+	FrameHeight int             `json:"frameHeight"`
+	Detections  []FaceDetection `json:"detections"`
+}
+
+// Incident lifecycle events
+const (
+	IncidentEventOpened  = "opened"
+	IncidentEventUpdated = "updated"
+	IncidentEventClosed  = "closed"
+)
+
+// IncidentSummary describes an incident: consecutive detections of a camera aggregated until a quiet period
+type IncidentSummary struct {
+	ID            string     `json:"id"`
+	OpenedAt      time.Time  `json:"openedAt"`
+	LastSeenAt    time.Time  `json:"lastSeenAt"`
+	ClosedAt      *time.Time `json:"closedAt,omitempty"`
+	PeakFaceCount int        `json:"peakFaceCount"`
+	UniqueFaces   int        `json:"uniqueFaces"`
+	MaxConfidence float32    `json:"maxConfidence"`
+	Severity      string     `json:"severity,omitempty"`
 }
 
-// OverlayConfig defines overlay rendering configuration
+// StoredEvent is a detection batch persisted in the local event store.
+// The store keeps the key of the event's snapshot; its URL is signed when the event is listed.
+type StoredEvent struct {
+	ID            string          `json:"id"`
+	CameraID      string          `json:"cameraId"`
+	Sequence      int64           `json:"sequence"`
+	CapturedAt    time.Time       `json:"capturedAt"`
+	FrameWidth    int             `json:"frameWidth"`
+	FrameHeight   int             `json:"frameHeight"`
+	FaceCount     int             `json:"faceCount"`
+	MaxConfidence float32         `json:"maxConfidence"`
+	Detections    []FaceDetection `json:"detections"`
+	SnapshotURL   *string         `json:"snapshotUrl"`
+	SnapshotKey   string          `json:"-"`
+}
+
+// EventsQuery holds the filters and pagination for querying stored events
+type EventsQuery struct {
+	CameraID string    `form:"cameraId"`
+	From     time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	MinFaces int       `form:"minFaces" binding:"omitempty,min=0"`
+	Page     int       `form:"page" binding:"omitempty,min=1"`
+	Limit    int       `form:"limit" binding:"omitempty,min=1,max=500"`
+}
+
+// Pagination describes a page of results
+type Pagination struct {
+	Page         int `json:"page"`
+	Limit        int `json:"limit"`
+	TotalRecords int `json:"totalRecords"`
+	TotalPages   int `json:"totalPages"`
+}
+
+// EventsResponse is a page of stored events
+type EventsResponse struct {
+	Data       []StoredEvent `json:"data"`
+	Pagination Pagination    `json:"pagination"`
+}
+
+// Overlay anchors: the frame corner an overlay element is stacked from
+const (
+	AnchorTopLeft     = "top-left"
+	AnchorTopRight    = "top-right"
+	AnchorBottomLeft  = "bottom-left"
+	AnchorBottomRight = "bottom-right"
+)
+
+// DefaultTimestampFormat is the default Go time layout of the overlay timestamp
+const DefaultTimestampFormat = "15:04:05"
+
+// OverlayConfig defines overlay rendering configuration.
+// LineWidth and FontSize are in pixels at a 720-line frame and scale with the frame height.
+// An empty anchor places the element at its default corner.
+// LogoFile names a PNG in the worker's logo directory; LogoScale is the logo height as a fraction of the frame height.
 type OverlayConfig struct {
 	Enabled            bool     `json:"enabled"`
 	DrawBoundingBox    bool     `json:"drawBoundingBox"`
@@ -22,7 +128,37 @@ type OverlayConfig struct {
 	ShowConfidence     bool     `json:"showConfidence"`
 	BoxColor           RGBColor `json:"boxColor"`
 	TextColor          RGBColor `json:"textColor"`
-	LineWidth          int      `json:"lineWidth"`
+	LineWidth          int      `json:"lineWidth" binding:"min=1,max=20"`
+	DrawZones          bool     `json:"drawZones"`
+	ShowTrackIDs       bool     `json:"showTrackIds"`
+	FontSize           int      `json:"fontSize" binding:"min=8,max=128"`
+	TextBackground     bool     `json:"textBackground"`
+	BackgroundColor    RGBColor `json:"backgroundColor"`
+	BackgroundAlpha    float64  `json:"backgroundAlpha" binding:"min=0,max=1"`
+	ShowCameraName     bool     `json:"showCameraName"`
+	ShowLocation       bool     `json:"showLocation"`
+	ShowTimestamp      bool     `json:"showTimestamp"`
+	CustomText         string   `json:"customText" binding:"max=200"`
+	TimestampFormat    string   `json:"timestampFormat" binding:"max=64"`
+	TimeZone           string   `json:"timeZone"`
+	CameraNameAnchor   string   `json:"cameraNameAnchor" binding:"omitempty,oneof=top-left top-right bottom-left bottom-right"`
+	LocationAnchor     string   `json:"locationAnchor" binding:"omitempty,oneof=top-left top-right bottom-left bottom-right"`
+	TimestampAnchor    string   `json:"timestampAnchor" binding:"omitempty,oneof=top-left top-right bottom-left bottom-right"`
+	FPSAnchor          string   `json:"fpsAnchor" binding:"omitempty,oneof=top-left top-right bottom-left bottom-right"`
+	FaceCountAnchor    string   `json:"faceCountAnchor" binding:"omitempty,oneof=top-left top-right bottom-left bottom-right"`
+	CustomTextAnchor   string   `json:"customTextAnchor" binding:"omitempty,oneof=top-left top-right bottom-left bottom-right"`
+	LogoFile           string   `json:"logoFile"`
+	LogoAnchor         string   `json:"logoAnchor" binding:"omitempty,oneof=top-left top-right bottom-left bottom-right"`
+	LogoScale          float64  `json:"logoScale" binding:"min=0,max=1"`
+	LogoOpacity        float64  `json:"logoOpacity" binding:"min=0,max=1"`
+	WatermarkText      string   `json:"watermarkText" binding:"max=200"`
+	WatermarkOpacity   float64  `json:"watermarkOpacity" binding:"min=0,max=1"`
+}
+
+// OverlayResponse is the response for reading or updating the overlay of a camera
+type OverlayResponse struct {
+	CameraID string        `json:"cameraId"`
+	Overlay  OverlayConfig `json:"overlay"`
 }
 
 // RGBColor represents a color in RGB format
@@ -38,6 +174,7 @@ type RGBColor struct {
 var (
 	ColorGreen = RGBColor{R: 0, G: 255, B: 0}
 	ColorWhite = RGBColor{R: 255, G: 255, B: 255}
+	ColorBlack = RGBColor{R: 0, G: 0, B: 0}
 )
 
 // ----------------------------------------------------------------------
@@ -53,6 +190,25 @@ func DefaultOverlayConfig() *OverlayConfig {
 		ShowConfidence:     false,
 		BoxColor:           ColorGreen,
 		TextColor:          ColorWhite,
-		LineWidth:          2,
+		LineWidth:          4,
+		ShowTrackIDs:       true,
+		FontSize:           28,
+		TextBackground:     false,
+		BackgroundColor:    ColorBlack,
+		BackgroundAlpha:    0.6,
+		ShowCameraName:     true,
+		ShowLocation:       true,
+		ShowTimestamp:      true,
+		TimestampFormat:    DefaultTimestampFormat,
+		CameraNameAnchor:   AnchorTopLeft,
+		LocationAnchor:     AnchorTopLeft,
+		TimestampAnchor:    AnchorBottomRight,
+		FPSAnchor:          AnchorTopRight,
+		FaceCountAnchor:    AnchorBottomLeft,
+		CustomTextAnchor:   AnchorTopRight,
+		LogoAnchor:         AnchorTopRight,
+		LogoScale:          0.1,
+		LogoOpacity:        1,
+		WatermarkOpacity:   0.25,
 	}
 }
@@ -0,0 +1,40 @@
+package models
+
+// ----------------------------------------------------------------------
+
+// Units of the face size limits
+const (
+	FaceSizeUnitPixels   = "pixels"
+	FaceSizeUnitFraction = "fraction"
+)
+
+// ----------------------------------------------------------------------
+
+// DetectionSettings are the face detection settings in effect for a camera.
+// Face sizes are box heights, in pixels or as a fraction of the frame height; 0 means no limit.
+type DetectionSettings struct {
+	MinConfidence        float32 `json:"minConfidence"`
+	InputWidth           int     `json:"inputWidth"`
+	InputHeight          int     `json:"inputHeight"`
+	AlertCooldownSeconds float64 `json:"alertCooldownSeconds"`
+	MinFaceSize          float64 `json:"minFaceSize"`
+	MaxFaceSize          float64 `json:"maxFaceSize"`
+	FaceSizeUnit         string  `json:"faceSizeUnit"`
+}
+
+// UpdateDetectionSettingsRequest changes the face detection settings of a camera; omitted fields keep their value
+type UpdateDetectionSettingsRequest struct {
+	MinConfidence        *float32 `json:"minConfidence,omitempty" binding:"omitempty,min=0,max=1"`
+	InputWidth           *int     `json:"inputWidth,omitempty" binding:"omitempty,min=32,max=1920"`
+	InputHeight          *int     `json:"inputHeight,omitempty" binding:"omitempty,min=32,max=1920"`
+	AlertCooldownSeconds *float64 `json:"alertCooldownSeconds,omitempty" binding:"omitempty,min=0,max=3600"`
+	MinFaceSize          *float64 `json:"minFaceSize,omitempty" binding:"omitempty,min=0"`
+	MaxFaceSize          *float64 `json:"maxFaceSize,omitempty" binding:"omitempty,min=0"`
+	FaceSizeUnit         *string  `json:"faceSizeUnit,omitempty" binding:"omitempty,oneof=pixels fraction"`
+}
+
+// DetectionSettingsResponse is the response for updating the face detection settings of a camera
+type DetectionSettingsResponse struct {
+	CameraID string            `json:"cameraId"`
+	Settings DetectionSettings `json:"settings"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// ----------------------------------------------------------------------
+
+// Privacy mask modes
+const (
+	PrivacyMaskModeFill     = "fill"
+	PrivacyMaskModePixelate = "pixelate"
+)
+
+// ----------------------------------------------------------------------
+
+// PrivacyMask is a named polygon that is blanked out of every published frame
+type PrivacyMask struct {
+	Name   string            `json:"name" binding:"required"`
+	Mode   string            `json:"mode,omitempty" binding:"omitempty,oneof=fill pixelate"`
+	Points []NormalizedPoint `json:"points" binding:"min=3,dive"`
+}
+
+// UpdatePrivacyMasksRequest is the request payload for replacing the privacy masks of a camera
+type UpdatePrivacyMasksRequest struct {
+	Masks []PrivacyMask `json:"masks" binding:"dive"`
+}
+
+// PrivacyMasksResponse is the response for updating the privacy masks of a camera
+type PrivacyMasksResponse struct {
+	CameraID string        `json:"cameraId"`
+	Masks    []PrivacyMask `json:"masks"`
+}
@@ -0,0 +1,48 @@
+package models
+
+// ----------------------------------------------------------------------
+
+// ProbeFailureKind classifies why a stream probe failed
+type ProbeFailureKind string
+
+// ----------------------------------------------------------------------
+
+const (
+	ProbeFailureDNS               ProbeFailureKind = "dns"
+	ProbeFailureConnectionRefused ProbeFailureKind = "connection_refused"
+	ProbeFailureUnauthorized      ProbeFailureKind = "unauthorized"
+	ProbeFailureUnsupportedCodec  ProbeFailureKind = "unsupported_codec"
+	ProbeFailureNoVideoStream     ProbeFailureKind = "no_video_stream"
+	ProbeFailureTimeout           ProbeFailureKind = "timeout"
+	ProbeFailureUnknown           ProbeFailureKind = "unknown"
+)
+
+// ----------------------------------------------------------------------
+
+// ProbeStreamRequest is the request payload for probing a stream
+type ProbeStreamRequest struct {
+	RTSPUrl   string `json:"rtspUrl" binding:"required"`
+	TimeoutMs int    `json:"timeoutMs" binding:"omitempty,min=1000,max=60000"`
+}
+
+// AudioTrackInfo describes an audio track found in a stream
+type AudioTrackInfo struct {
+	Index         int    `json:"index"`
+	Codec         string `json:"codec"`
+	SampleRate    int    `json:"sampleRate"`
+	Channels      int    `json:"channels"`
+	ChannelLayout string `json:"channelLayout,omitempty"`
+}
+
+// ProbeStreamResponse contains the diagnostics gathered for a stream
+type ProbeStreamResponse struct {
+	Codec                 string           `json:"codec"`
+	Profile               string           `json:"profile,omitempty"`
+	Width                 int              `json:"width"`
+	Height                int              `json:"height"`
+	FPS                   float64          `json:"fps"`
+	BitrateKbps           int              `json:"bitrateKbps,omitempty"`
+	AudioTracks           []AudioTrackInfo `json:"audioTracks"`
+	ProbeDurationMs       int64            `json:"probeDurationMs"`
+	LatencyToFirstFrameMs int64            `json:"latencyToFirstFrameMs,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+// ----------------------------------------------------------------------
+
+// ONVIFConfig holds the ONVIF connection details for a PTZ camera
+type ONVIFConfig struct {
+	ServiceURL       string `json:"serviceUrl" binding:"required"`
+	Username         string `json:"username,omitempty"`
+	Password         string `json:"password,omitempty"`
+	ProfileToken     string `json:"profileToken,omitempty"`
+	AlertPresetToken string `json:"alertPresetToken,omitempty"`
+}
+
+// PTZAction identifies a PTZ command
+type PTZAction string
+
+// ----------------------------------------------------------------------
+
+const (
+	PTZActionContinuousMove PTZAction = "continuousMove"
+	PTZActionStop           PTZAction = "stop"
+	PTZActionAbsoluteMove   PTZAction = "absoluteMove"
+	PTZActionRelativeMove   PTZAction = "relativeMove"
+	PTZActionGotoPreset     PTZAction = "gotoPreset"
+)
+
+// ----------------------------------------------------------------------
+
+// PTZControlRequest is the request payload for controlling a PTZ camera
+type PTZControlRequest struct {
+	Action      PTZAction `json:"action" binding:"required,oneof=continuousMove stop absoluteMove relativeMove gotoPreset"`
+	Pan         float64   `json:"pan"`
+	Tilt        float64   `json:"tilt"`
+	Zoom        float64   `json:"zoom"`
+	Speed       *float64  `json:"speed,omitempty"`
+	TimeoutMs   int       `json:"timeoutMs,omitempty"`
+	PresetToken string    `json:"presetToken,omitempty"`
+}
+
+// PTZPosition represents a pan/tilt/zoom position in ONVIF generic space
+type PTZPosition struct {
+	Pan  float64 `json:"pan"`
+	Tilt float64 `json:"tilt"`
+	Zoom float64 `json:"zoom"`
+}
+
+// PTZPreset represents a preset stored on a PTZ camera
+type PTZPreset struct {
+	Token    string       `json:"token"`
+	Name     string       `json:"name"`
+	Position *PTZPosition `json:"position,omitempty"`
+}
+
+// PTZControlResponse is the response for a PTZ command
+type PTZControlResponse struct {
+	CameraID string    `json:"cameraId"`
+	Action   PTZAction `json:"action"`
+}
+
+// PTZPresetsResponse is the response for listing PTZ presets
+type PTZPresetsResponse struct {
+	CameraID string      `json:"cameraId"`
+	Presets  []PTZPreset `json:"presets"`
+}
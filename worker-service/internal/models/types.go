@@ -2,6 +2,10 @@ package models
 
 // ----------------------------------------------------------------------
 
+import "time"
+
+// ----------------------------------------------------------------------
+
 // Camera represents a camera managed by the worker
 type Camera struct {
 	ID       string `json:"id"`
@@ -27,11 +31,27 @@ const (
 
 // StartStreamRequest is the request payload for starting a stream
 type StartStreamRequest struct {
-	CameraID             string `json:"cameraId" binding:"required"`
-	Name                 string `json:"name" binding:"required"`
-	RTSPUrl              string `json:"rtspUrl" binding:"required"`
-	Location             string `json:"location" binding:"required"`
-	FaceDetectionEnabled bool   `json:"faceDetectionEnabled"`
+	CameraID             string                `json:"cameraId" binding:"required"`
+	Name                 string                `json:"name" binding:"required"`
+	RTSPUrl              string                `json:"rtspUrl" binding:"required"`
+	Location             string                `json:"location" binding:"required"`
+	FaceDetectionEnabled bool                  `json:"faceDetectionEnabled"`
+	ONVIF                *ONVIFConfig          `json:"onvif,omitempty"`
+	Recording            *RecordingOptions     `json:"recording,omitempty"`
+	AlertRules           *AlertRuleSet         `json:"alertRules,omitempty"`
+	AlertSinks           []AlertSinkConfig     `json:"alertSinks,omitempty" binding:"dive"`
+	Zones                []DetectionZone       `json:"zones,omitempty" binding:"dive"`
+	PrivacyMasks         []PrivacyMask         `json:"privacyMasks,omitempty" binding:"dive"`
+	Anonymization        *AnonymizationOptions `json:"anonymization,omitempty"`
+
+	// DetectionSettings tunes face detection and the alert cooldown
+	DetectionSettings *UpdateDetectionSettingsRequest `json:"detectionSettings,omitempty"`
+
+	// Overlay replaces the default overlay configuration
+	Overlay *OverlayConfig `json:"overlay,omitempty"`
+
+	// AllowFallbackDimensions starts the stream at 640x480@15 when probing fails
+	AllowFallbackDimensions bool `json:"allowFallbackDimensions"`
 }
 
 // StartStreamResponse is the response for starting a stream
@@ -76,6 +96,13 @@ type StreamStatusResponse struct {
 	DropRate        float64      `json:"dropRate"`
 	TargetFPS       int          `json:"targetFPS"`
 	DetectedFPS     int          `json:"detectedFPS"`
+	PTZEnabled      bool         `json:"ptzEnabled"`
+	MJPEGClients    int          `json:"mjpegClients"`
+	Recording       string       `json:"recording,omitempty"`
+	Anonymization   string       `json:"anonymization,omitempty"`
+
+	DetectionSettings DetectionSettings `json:"detectionSettings"`
+	Tracking          TrackingStats     `json:"tracking"`
 }
 
 // StreamDetail provides detailed information about a single stream
@@ -101,6 +128,18 @@ type HealthCheckResponse struct {
 	UtilizationPercent    float64                 `json:"utilizationPercent"`
 	StreamSessions        map[string]StreamStatus `json:"streamSessions"`
 	StreamDetails         []StreamDetail          `json:"streamDetails"`
+	AlertOutbox           *AlertOutboxStats       `json:"alertOutbox,omitempty"`
+}
+
+// AlertOutboxStats reports the depth and delivery counters of the alert outbox
+type AlertOutboxStats struct {
+	Pending      int                       `json:"pending"`
+	DeadLettered int                       `json:"deadLettered"`
+	Delivered    int64                     `json:"delivered"`
+	Failures     int64                     `json:"failures"`
+	LastError    string                    `json:"lastError,omitempty"`
+	LastErrorAt  *time.Time                `json:"lastErrorAt,omitempty"`
+	Sinks        map[string]AlertSinkStats `json:"sinks,omitempty"`
 }
 
 // ToggleFaceDetectionRequest is the request payload for toggling face detection
@@ -112,3 +151,61 @@ type ToggleFaceDetectionRequest struct {
 type UpdateFPSRequest struct {
 	TargetFPS int `json:"targetFPS" binding:"required,min=1,max=30"`
 }
+
+// Snapshot formats and variants
+const (
+	SnapshotFormatJPEG = "jpeg"
+	SnapshotFormatPNG  = "png"
+
+	SnapshotVariantRaw       = "raw"
+	SnapshotVariantAnnotated = "annotated"
+)
+
+// SnapshotQuery holds the query options for fetching a frame snapshot
+type SnapshotQuery struct {
+	Format   string `form:"format" binding:"omitempty,oneof=jpeg png"`
+	Variant  string `form:"variant" binding:"omitempty,oneof=raw annotated"`
+	MaxWidth int    `form:"maxWidth" binding:"omitempty,min=16,max=7680"`
+	Quality  int    `form:"quality" binding:"omitempty,min=1,max=100"`
+}
+
+// MJPEGQuery holds the query options for an MJPEG preview stream
+type MJPEGQuery struct {
+	FPS      int    `form:"fps" binding:"omitempty,min=1,max=30"`
+	Variant  string `form:"variant" binding:"omitempty,oneof=raw annotated"`
+	MaxWidth int    `form:"maxWidth" binding:"omitempty,min=16,max=7680"`
+	Quality  int    `form:"quality" binding:"omitempty,min=1,max=100"`
+}
+
+// Recording sources
+const (
+	RecordingSourceProcessed = "processed"
+	RecordingSourceRaw       = "raw"
+)
+
+// RecordingOptions enables continuous recording of a stream
+type RecordingOptions struct {
+	Enabled bool   `json:"enabled"`
+	Source  string `json:"source" binding:"omitempty,oneof=processed raw"`
+}
+
+// RecordingsQuery holds the time range for listing recording segments
+type RecordingsQuery struct {
+	From time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To   time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// RecordingSegment describes a recorded segment on disk
+type RecordingSegment struct {
+	FileName        string    `json:"fileName"`
+	StartTime       time.Time `json:"startTime"`
+	EndTime         time.Time `json:"endTime"`
+	DurationSeconds float64   `json:"durationSeconds"`
+	SizeBytes       int64     `json:"sizeBytes"`
+}
+
+// RecordingsResponse is the response for listing recording segments
+type RecordingsResponse struct {
+	CameraID string             `json:"cameraId"`
+	Segments []RecordingSegment `json:"segments"`
+}
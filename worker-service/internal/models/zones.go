@@ -0,0 +1,37 @@
+package models
+
+// ----------------------------------------------------------------------
+
+// Detection zone types
+const (
+	ZoneTypeInclude = "include"
+	ZoneTypeExclude = "exclude"
+)
+
+// ----------------------------------------------------------------------
+
+// NormalizedPoint is a point in frame coordinates scaled to 0..1
+type NormalizedPoint struct {
+	X float64 `json:"x" binding:"min=0,max=1"`
+	Y float64 `json:"y" binding:"min=0,max=1"`
+}
+
+// DetectionZone is a named polygon that includes or excludes an area of the frame
+type DetectionZone struct {
+	Name   string            `json:"name" binding:"required"`
+	Type   string            `json:"type" binding:"required,oneof=include exclude"`
+	Points []NormalizedPoint `json:"points" binding:"min=3,dive"`
+}
+
+// UpdateZonesRequest is the request payload for replacing the detection zones of a camera
+type UpdateZonesRequest struct {
+	Zones     []DetectionZone `json:"zones" binding:"dive"`
+	DrawZones *bool           `json:"drawZones,omitempty"`
+}
+
+// ZonesResponse is the response for updating the detection zones of a camera
+type ZonesResponse struct {
+	CameraID  string          `json:"cameraId"`
+	Zones     []DetectionZone `json:"zones"`
+	DrawZones bool            `json:"drawZones"`
+}
@@ -0,0 +1,724 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"worker-service/internal/config"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	outboxPollInterval        = 2 * time.Second
+	outboxBatchSize           = 10
+	outboxBaseRetryDelay      = 2 * time.Second
+	outboxMaxBackoffShift     = 16
+	outboxDeadLetterRetention = 7 * 24 * time.Hour
+	outboxPruneInterval       = 1 * time.Hour
+)
+
+const alertOutboxSchema = `
+CREATE TABLE IF NOT EXISTS alert_outbox (
+	id               TEXT    NOT NULL,
+	sink             TEXT    NOT NULL,
+	sink_config      TEXT    NOT NULL,
+	camera_id        TEXT    NOT NULL,
+	camera_name      TEXT    NOT NULL,
+	event_id         TEXT    NOT NULL DEFAULT '',
+	event_type       TEXT    NOT NULL,
+	incident_id      TEXT    NOT NULL,
+	incident         TEXT    NOT NULL,
+	detections       TEXT    NOT NULL,
+	created_at       INTEGER NOT NULL,
+	snapshot         BLOB,
+	snapshot_key     TEXT    NOT NULL DEFAULT '',
+	annotated_image  BLOB,
+	annotated_key    TEXT    NOT NULL DEFAULT '',
+	face_crops       TEXT    NOT NULL DEFAULT '[]',
+	face_crop_keys   TEXT    NOT NULL DEFAULT '[]',
+	clip_path        TEXT    NOT NULL DEFAULT '',
+	clip_seconds     REAL    NOT NULL DEFAULT 0,
+	clip_key         TEXT    NOT NULL DEFAULT '',
+	attempts         INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at  INTEGER NOT NULL,
+	last_error       TEXT    NOT NULL DEFAULT '',
+	dead_lettered_at INTEGER,
+	PRIMARY KEY (id, sink)
+);
+CREATE INDEX IF NOT EXISTS idx_alert_outbox_due ON alert_outbox (dead_lettered_at, next_attempt_at);
+`
+
+// alertOutboxIndexes are created once older databases have been migrated, as they index added columns
+const alertOutboxIndexes = `
+CREATE INDEX IF NOT EXISTS idx_alert_outbox_incident ON alert_outbox (incident_id, sink);
+`
+
+// alertOutboxAddedColumns are added to outbox databases created before these columns existed
+var alertOutboxAddedColumns = []string{
+	"event_type TEXT NOT NULL DEFAULT 'opened'",
+	"incident_id TEXT NOT NULL DEFAULT ''",
+	"incident TEXT NOT NULL DEFAULT '{}'",
+	"annotated_image BLOB",
+	"annotated_key TEXT NOT NULL DEFAULT ''",
+	"face_crops TEXT NOT NULL DEFAULT '[]'",
+	"face_crop_keys TEXT NOT NULL DEFAULT '[]'",
+}
+
+// alertOutboxRenamedColumns map the media URL columns of older outbox databases to the key columns replacing them
+var alertOutboxRenamedColumns = [][2]string{
+	{"snapshot_url", "snapshot_key"},
+	{"annotated_url", "annotated_key"},
+	{"face_crop_urls", "face_crop_keys"},
+	{"clip_url", "clip_key"},
+}
+
+// ----------------------------------------------------------------------
+
+// outboxAlert is the delivery of an alert to one sink. Its ID doubles as the idempotency key
+// and is shared by the deliveries to every sink; the keys of uploaded media are kept so retries never upload
+// the same file twice. Their URLs are built on each attempt, as signed URLs expire.
+// FaceCrops and FaceCropKeys are aligned with Detections.
+type outboxAlert struct {
+	ID                   string
+	Sink                 models.AlertSinkConfig
+	CameraID             string
+	CameraName           string
+	EventID              string
+	Event                string
+	Incident             models.IncidentSummary
+	Detections           []models.FaceDetection
+	CreatedAt            time.Time
+	Snapshot             []byte
+	SnapshotKey          string
+	AnnotatedSnapshot    []byte
+	AnnotatedSnapshotKey string
+	FaceCrops            [][]byte
+	FaceCropKeys         []string
+	ClipPath             string
+	ClipSeconds          float64
+	ClipKey              string
+	Attempts             int
+}
+
+// AlertOutbox persists alerts before delivery and retries them with exponential backoff.
+// Each sink of an alert is a separate delivery, retried and dead-lettered on its own.
+type AlertOutbox struct {
+	db          *sql.DB
+	clipDir     string
+	maxAttempts int
+	maxBackoff  time.Duration
+	deliver     func(alert *outboxAlert, finalAttempt bool) error
+
+	wake      chan struct{}
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+
+	delivered   int64
+	failures    int64
+	errorMutex  sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// NewAlertOutbox opens (or creates) the outbox database; deliver sends a single alert
+func NewAlertOutbox(cfg *config.Config, deliver func(alert *outboxAlert, finalAttempt bool) error) (*AlertOutbox, error) {
+	clipDir := filepath.Join(cfg.AlertOutboxDir, "clips")
+	if err := os.MkdirAll(clipDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create outbox clip directory: %w", err)
+	}
+
+	db, err := openSQLite(filepath.Join(cfg.AlertOutboxDir, "outbox.db"), alertOutboxSchema)
+	if err != nil {
+		return nil, fmt.Errorf("alert outbox: %w", err)
+	}
+
+	if err := migrateAlertOutbox(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("alert outbox: %w", err)
+	}
+
+	return &AlertOutbox{
+		db:          db,
+		clipDir:     clipDir,
+		maxAttempts: cfg.AlertOutboxMaxAttempts,
+		maxBackoff:  time.Duration(cfg.AlertOutboxMaxBackoffSeconds) * time.Second,
+		deliver:     deliver,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}, nil
+}
+
+// migrateAlertOutbox brings an outbox database created by an older worker to the current schema
+func migrateAlertOutbox(db *sql.DB) error {
+	renamed := false
+	for _, column := range alertOutboxRenamedColumns {
+		ok, err := renameSQLiteColumn(db, "alert_outbox", column[0], column[1])
+		if err != nil {
+			return err
+		}
+		renamed = renamed || ok
+	}
+
+	if err := addSQLiteColumns(db, "alert_outbox", alertOutboxAddedColumns); err != nil {
+		return err
+	}
+
+	// Media URLs of older outboxes may have expired; their media are uploaded again on the next attempt
+	if renamed {
+		if _, err := db.Exec(`UPDATE alert_outbox
+			SET snapshot_key = '', annotated_key = '', face_crop_keys = '[]', clip_key = ''`); err != nil {
+			return fmt.Errorf("failed to clear media URLs: %w", err)
+		}
+	}
+
+	if err := migrateAlertOutboxSinks(db); err != nil {
+		return fmt.Errorf("failed to migrate alerts to sinks: %w", err)
+	}
+
+	// Alerts queued before incidents existed each become an incident of their own
+	if _, err := db.Exec(`UPDATE alert_outbox
+		SET incident_id = id, incident = json_object('id', id, 'peakFaceCount', json_array_length(detections))
+		WHERE incident_id = ''`); err != nil {
+		return fmt.Errorf("failed to migrate alerts without incident: %w", err)
+	}
+
+	if _, err := db.Exec(alertOutboxIndexes); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+	return nil
+}
+
+// migrateAlertOutboxSinks rebuilds an outbox created before alerts had a delivery per sink, whose primary
+// key is the alert ID alone; its alerts become deliveries to the backend sink, in their original order
+func migrateAlertOutboxSinks(db *sql.DB) error {
+	columns, err := sqliteColumns(db, "alert_outbox")
+	if err != nil {
+		return err
+	}
+	if columns["sink"] {
+		return nil
+	}
+
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	copied := strings.Join(names, ", ")
+
+	sinkConfig, err := json.Marshal(backendSinkConfig)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Indexes keep their names when their table is renamed, so they are dropped and recreated on the new table
+	statements := []string{
+		"DROP INDEX IF EXISTS idx_alert_outbox_due",
+		"DROP INDEX IF EXISTS idx_alert_outbox_incident",
+		"ALTER TABLE alert_outbox RENAME TO alert_outbox_legacy",
+		alertOutboxSchema,
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf(`INSERT INTO alert_outbox (%s, sink, sink_config)
+		SELECT %s, ?, ? FROM alert_outbox_legacy ORDER BY rowid`, copied, copied),
+		backendSinkConfig.Name, string(sinkConfig)); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec("DROP TABLE alert_outbox_legacy"); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	utils.GetLogger().Info("Alert outbox migrated to per-sink deliveries")
+	return nil
+}
+
+// ----------------------------------------------------------------------
+
+// Start runs the background sender until Close is called
+func (o *AlertOutbox) Start() {
+	o.done.Add(1)
+	go o.runSender()
+}
+
+// Close stops the sender and closes the database; undelivered alerts are picked up on the next start
+func (o *AlertOutbox) Close() {
+	o.closeOnce.Do(func() {
+		close(o.stop)
+		o.done.Wait()
+
+		if err := o.db.Close(); err != nil {
+			utils.GetLogger().Warnf("[Outbox] Failed to close database: %v", err)
+		}
+	})
+}
+
+// Enqueue persists one delivery of an alert per sink, moving its clip into the outbox so it outlives the stream
+func (o *AlertOutbox) Enqueue(alert *outboxAlert, sinks []models.AlertSinkConfig) error {
+	if len(sinks) == 0 {
+		if alert.ClipPath != "" {
+			os.Remove(alert.ClipPath)
+		}
+		return nil
+	}
+
+	if alert.ClipPath != "" {
+		clipPath := filepath.Join(o.clipDir, alert.ID+filepath.Ext(alert.ClipPath))
+		if err := moveFile(alert.ClipPath, clipPath); err != nil {
+			utils.GetLogger().Warnf("[Outbox] Dropping clip of alert %s: %v", alert.ID, err)
+			os.Remove(alert.ClipPath)
+			alert.ClipPath = ""
+		} else {
+			alert.ClipPath = clipPath
+		}
+	}
+
+	detections, err := json.Marshal(alert.Detections)
+	if err != nil {
+		return fmt.Errorf("failed to encode detections: %w", err)
+	}
+
+	incident, err := json.Marshal(alert.Incident)
+	if err != nil {
+		return fmt.Errorf("failed to encode incident: %w", err)
+	}
+
+	faceCrops, err := json.Marshal(alert.FaceCrops)
+	if err != nil {
+		return fmt.Errorf("failed to encode face crops: %w", err)
+	}
+
+	if err := o.insert(alert, sinks, string(incident), string(detections), string(faceCrops)); err != nil {
+		if alert.ClipPath != "" {
+			os.Remove(alert.ClipPath)
+		}
+		return fmt.Errorf("failed to store alert in outbox: %w", err)
+	}
+
+	// Deliver right away instead of waiting for the next poll
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+func (o *AlertOutbox) insert(alert *outboxAlert, sinks []models.AlertSinkConfig, incident, detections, faceCrops string) error {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO alert_outbox
+		(id, sink, sink_config, camera_id, camera_name, event_id, event_type, incident_id, incident, detections, created_at,
+		snapshot, annotated_image, face_crops, clip_path, clip_seconds, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, sink := range sinks {
+		sinkConfig, err := json.Marshal(sink)
+		if err != nil {
+			return err
+		}
+
+		if _, err := stmt.Exec(alert.ID, sink.Name, string(sinkConfig), alert.CameraID, alert.CameraName, alert.EventID, alert.Event,
+			alert.Incident.ID, incident, detections, alert.CreatedAt.UnixMilli(), alert.Snapshot, alert.AnnotatedSnapshot, faceCrops,
+			alert.ClipPath, alert.ClipSeconds, time.Now().UnixMilli()); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Stats returns the outbox depth and delivery counters, in total and per sink
+func (o *AlertOutbox) Stats() *models.AlertOutboxStats {
+	stats := &models.AlertOutboxStats{
+		Delivered: atomic.LoadInt64(&o.delivered),
+		Failures:  atomic.LoadInt64(&o.failures),
+		Sinks:     make(map[string]models.AlertSinkStats),
+	}
+
+	rows, err := o.db.Query(`SELECT sink,
+		COUNT(CASE WHEN dead_lettered_at IS NULL THEN 1 END),
+		COUNT(dead_lettered_at)
+		FROM alert_outbox GROUP BY sink`)
+	if err != nil {
+		utils.GetLogger().Warnf("[Outbox] Failed to count alerts: %v", err)
+	} else {
+		for rows.Next() {
+			var sink string
+			var sinkStats models.AlertSinkStats
+			if err := rows.Scan(&sink, &sinkStats.Pending, &sinkStats.DeadLettered); err != nil {
+				utils.GetLogger().Warnf("[Outbox] Failed to count alerts: %v", err)
+				break
+			}
+			stats.Sinks[sink] = sinkStats
+			stats.Pending += sinkStats.Pending
+			stats.DeadLettered += sinkStats.DeadLettered
+		}
+		rows.Close()
+	}
+
+	o.errorMutex.Lock()
+	if o.lastError != "" {
+		lastErrorAt := o.lastErrorAt
+		stats.LastError = o.lastError
+		stats.LastErrorAt = &lastErrorAt
+	}
+	o.errorMutex.Unlock()
+
+	return stats
+}
+
+// ----------------------------------------------------------------------
+
+func (o *AlertOutbox) runSender() {
+	defer o.done.Done()
+
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	lastPrune := time.Time{}
+	for {
+		select {
+		case <-o.stop:
+			return
+		case <-ticker.C:
+		case <-o.wake:
+		}
+
+		o.deliverDue()
+
+		if time.Since(lastPrune) >= outboxPruneInterval {
+			o.pruneDeadLetters()
+			lastPrune = time.Now()
+		}
+	}
+}
+
+// deliverDue attempts every delivery whose retry time has come, stopping early on shutdown
+func (o *AlertOutbox) deliverDue() {
+	for {
+		alerts, err := o.dueAlerts()
+		if err != nil {
+			utils.GetLogger().Errorf("[Outbox] Failed to load due alerts: %v", err)
+			return
+		}
+
+		// Deliveries of one alert loaded together share the media uploaded by the first of them
+		uploaded := make(map[string]*outboxAlert)
+		for _, alert := range alerts {
+			select {
+			case <-o.stop:
+				return
+			default:
+			}
+
+			if earlier, ok := uploaded[alert.ID]; ok {
+				alert.adoptMediaKeys(earlier)
+			}
+			o.attempt(alert)
+			uploaded[alert.ID] = alert
+		}
+
+		if len(alerts) < outboxBatchSize {
+			return
+		}
+	}
+}
+
+func (o *AlertOutbox) dueAlerts() ([]*outboxAlert, error) {
+	// Events of one incident are delivered to a sink in order: an event waits while an earlier one is still pending
+	rows, err := o.db.Query(`SELECT id, sink_config, camera_id, camera_name, event_id, event_type, incident, detections, created_at,
+		snapshot, snapshot_key, annotated_image, annotated_key, face_crops, face_crop_keys,
+		clip_path, clip_seconds, clip_key, attempts
+		FROM alert_outbox AS a
+		WHERE dead_lettered_at IS NULL AND next_attempt_at <= ?
+		AND NOT EXISTS (
+			SELECT 1 FROM alert_outbox AS earlier
+			WHERE earlier.incident_id = a.incident_id AND earlier.sink = a.sink AND earlier.rowid < a.rowid
+			AND earlier.dead_lettered_at IS NULL
+		)
+		ORDER BY rowid
+		LIMIT ?`, time.Now().UnixMilli(), outboxBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []*outboxAlert
+	for rows.Next() {
+		alert := &outboxAlert{}
+		var sinkConfig, incident, detections, faceCrops, faceCropKeys string
+		var createdAt int64
+
+		if err := rows.Scan(&alert.ID, &sinkConfig, &alert.CameraID, &alert.CameraName, &alert.EventID, &alert.Event, &incident, &detections,
+			&createdAt, &alert.Snapshot, &alert.SnapshotKey, &alert.AnnotatedSnapshot, &alert.AnnotatedSnapshotKey, &faceCrops, &faceCropKeys,
+			&alert.ClipPath, &alert.ClipSeconds, &alert.ClipKey, &alert.Attempts); err != nil {
+			return nil, err
+		}
+
+		alert.CreatedAt = time.UnixMilli(createdAt).UTC()
+		if err := json.Unmarshal([]byte(sinkConfig), &alert.Sink); err != nil {
+			return nil, fmt.Errorf("failed to decode sink of alert %s: %w", alert.ID, err)
+		}
+		if err := json.Unmarshal([]byte(incident), &alert.Incident); err != nil {
+			return nil, fmt.Errorf("failed to decode incident of alert %s: %w", alert.ID, err)
+		}
+		if err := json.Unmarshal([]byte(detections), &alert.Detections); err != nil {
+			return nil, fmt.Errorf("failed to decode detections of alert %s: %w", alert.ID, err)
+		}
+		if err := json.Unmarshal([]byte(faceCrops), &alert.FaceCrops); err != nil {
+			return nil, fmt.Errorf("failed to decode face crops of alert %s: %w", alert.ID, err)
+		}
+		if err := json.Unmarshal([]byte(faceCropKeys), &alert.FaceCropKeys); err != nil {
+			return nil, fmt.Errorf("failed to decode face crop keys of alert %s: %w", alert.ID, err)
+		}
+
+		alerts = append(alerts, alert)
+	}
+
+	return alerts, rows.Err()
+}
+
+// attempt delivers one alert to its sink and records the outcome
+func (o *AlertOutbox) attempt(alert *outboxAlert) {
+	logger := utils.GetLogger()
+
+	err := o.deliver(alert, alert.Attempts+1 >= o.maxAttempts)
+
+	// Share the keys of uploaded media with the other sinks of the alert so they do not upload again
+	if alert.hasMediaKeys() {
+		faceCropKeys, _ := json.Marshal(alert.FaceCropKeys)
+		if _, dbErr := o.db.Exec(`UPDATE alert_outbox
+			SET snapshot_key = ?, annotated_key = ?, face_crop_keys = ?, clip_key = ?
+			WHERE id = ?`,
+			alert.SnapshotKey, alert.AnnotatedSnapshotKey, string(faceCropKeys), alert.ClipKey, alert.ID); dbErr != nil {
+			logger.Errorf("[Outbox] Failed to store media keys of alert %s: %v", alert.ID, dbErr)
+		}
+	}
+
+	if err == nil {
+		if _, dbErr := o.db.Exec(`DELETE FROM alert_outbox WHERE id = ? AND sink = ?`, alert.ID, alert.Sink.Name); dbErr != nil {
+			logger.Errorf("[Outbox] Failed to remove delivered alert %s (sink %s): %v", alert.ID, alert.Sink.Name, dbErr)
+		}
+		o.removeUnusedClip(alert.ClipPath)
+		atomic.AddInt64(&o.delivered, 1)
+		return
+	}
+
+	alert.Attempts++
+	atomic.AddInt64(&o.failures, 1)
+	o.recordError(err)
+
+	var deadLetteredAt interface{}
+	nextAttemptAt := time.Now()
+	if !isRetryableDeliveryError(err) || alert.Attempts >= o.maxAttempts {
+		deadLetteredAt = time.Now().UnixMilli()
+		logger.Errorf("[Outbox] Alert %s for camera %s dead-lettered for sink %s after %d attempts: %v",
+			alert.ID, alert.CameraID, alert.Sink.Name, alert.Attempts, err)
+	} else {
+		delay := o.retryDelay(alert.Attempts)
+		nextAttemptAt = nextAttemptAt.Add(delay)
+		logger.Warnf("[Outbox] Alert %s for camera %s failed for sink %s (attempt %d/%d), retrying in %v: %v",
+			alert.ID, alert.CameraID, alert.Sink.Name, alert.Attempts, o.maxAttempts, delay.Round(time.Millisecond), err)
+	}
+
+	if _, dbErr := o.db.Exec(`UPDATE alert_outbox
+		SET attempts = ?, next_attempt_at = ?, last_error = ?, dead_lettered_at = ?
+		WHERE id = ? AND sink = ?`,
+		alert.Attempts, nextAttemptAt.UnixMilli(), err.Error(), deadLetteredAt, alert.ID, alert.Sink.Name,
+	); dbErr != nil {
+		logger.Errorf("[Outbox] Failed to update alert %s (sink %s): %v", alert.ID, alert.Sink.Name, dbErr)
+	}
+}
+
+// hasMediaKeys reports whether any media of the alert has been uploaded
+func (a *outboxAlert) hasMediaKeys() bool {
+	if a.SnapshotKey != "" || a.AnnotatedSnapshotKey != "" || a.ClipKey != "" {
+		return true
+	}
+	for _, key := range a.FaceCropKeys {
+		if key != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// adoptMediaKeys takes over the media another delivery of the same alert has uploaded
+func (a *outboxAlert) adoptMediaKeys(earlier *outboxAlert) {
+	if a.SnapshotKey == "" {
+		a.SnapshotKey = earlier.SnapshotKey
+	}
+	if a.AnnotatedSnapshotKey == "" {
+		a.AnnotatedSnapshotKey = earlier.AnnotatedSnapshotKey
+	}
+	for i, key := range earlier.FaceCropKeys {
+		if i >= len(a.FaceCropKeys) {
+			a.FaceCropKeys = append(a.FaceCropKeys, key)
+		} else if a.FaceCropKeys[i] == "" {
+			a.FaceCropKeys[i] = key
+		}
+	}
+	if a.ClipKey == "" {
+		a.ClipKey = earlier.ClipKey
+	}
+}
+
+// removeUnusedClip deletes an outbox clip once no delivery references it any more
+func (o *AlertOutbox) removeUnusedClip(clipPath string) {
+	if clipPath == "" {
+		return
+	}
+
+	var references int
+	if err := o.db.QueryRow(`SELECT COUNT(*) FROM alert_outbox WHERE clip_path = ?`, clipPath).Scan(&references); err != nil {
+		utils.GetLogger().Warnf("[Outbox] Failed to check clip %s: %v", clipPath, err)
+		return
+	}
+	if references == 0 {
+		os.Remove(clipPath)
+	}
+}
+
+// retryDelay returns the capped exponential backoff with jitter for the given attempt count
+func (o *AlertOutbox) retryDelay(attempts int) time.Duration {
+	shift := attempts - 1
+	if shift > outboxMaxBackoffShift {
+		shift = outboxMaxBackoffShift
+	}
+
+	delay := outboxBaseRetryDelay * time.Duration(1<<uint(shift))
+	if delay > o.maxBackoff {
+		delay = o.maxBackoff
+	}
+
+	jitterRange := float64(delay) * float64(jitterPercent) / 100.0
+	return delay + time.Duration(rand.Float64()*jitterRange*2-jitterRange)
+}
+
+func (o *AlertOutbox) recordError(err error) {
+	o.errorMutex.Lock()
+	defer o.errorMutex.Unlock()
+
+	o.lastError = err.Error()
+	o.lastErrorAt = time.Now().UTC()
+}
+
+// pruneDeadLetters deletes dead letters past their retention together with their clips
+func (o *AlertOutbox) pruneDeadLetters() {
+	cutoff := time.Now().Add(-outboxDeadLetterRetention).UnixMilli()
+
+	rows, err := o.db.Query(`SELECT DISTINCT clip_path FROM alert_outbox WHERE dead_lettered_at < ? AND clip_path != ''`, cutoff)
+	if err != nil {
+		utils.GetLogger().Warnf("[Outbox] Failed to prune dead letters: %v", err)
+		return
+	}
+
+	var clipPaths []string
+	for rows.Next() {
+		var clipPath string
+		if rows.Scan(&clipPath) == nil {
+			clipPaths = append(clipPaths, clipPath)
+		}
+	}
+	rows.Close()
+
+	if _, err := o.db.Exec(`DELETE FROM alert_outbox WHERE dead_lettered_at < ?`, cutoff); err != nil {
+		utils.GetLogger().Warnf("[Outbox] Failed to prune dead letters: %v", err)
+		return
+	}
+
+	for _, clipPath := range clipPaths {
+		o.removeUnusedClip(clipPath)
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// isRetryableDeliveryError reports whether a failed delivery may succeed later;
+// HTTP client errors, permanent SMTP replies and errors marked permanent by a sink are not retried
+func isRetryableDeliveryError(err error) bool {
+	var permanentErr *permanentDeliveryError
+	if errors.As(err, &permanentErr) {
+		return false
+	}
+
+	var statusErr *utils.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Retryable()
+	}
+
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code < 500
+	}
+	return true
+}
+
+// moveFile renames a file, falling back to copy and delete across filesystems
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+
+	return os.Remove(src)
+}
@@ -0,0 +1,249 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+// alertRuleMissGrace is how long a rule keeps its dwell timer through frames where it does not match,
+// the same time the face tracker keeps a face it lost, so detection flicker does not restart the dwell
+const alertRuleMissGrace = trackLostTimeout
+
+// ----------------------------------------------------------------------
+
+var alertRuleDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+var alertSeverityRank = map[string]int{
+	models.AlertSeverityLow:      1,
+	models.AlertSeverityMedium:   2,
+	models.AlertSeverityHigh:     3,
+	models.AlertSeverityCritical: 4,
+}
+
+// ----------------------------------------------------------------------
+
+// AlertRuleEngine evaluates the alert rules of a camera against each processed frame
+type AlertRuleEngine struct {
+	mutex    sync.Mutex
+	ruleSet  models.AlertRuleSet
+	location *time.Location
+	rules    []*compiledAlertRule
+}
+
+// compiledAlertRule is a validated rule with its dwell state
+type compiledAlertRule struct {
+	rule          models.AlertRule
+	windows       [][2]int // minutes since midnight
+	days          map[time.Weekday]bool
+	matchingSince time.Time
+	lastMatchedAt time.Time
+}
+
+// NewAlertRuleEngine creates a rule engine with no rules, which alerts on every detection
+func NewAlertRuleEngine() *AlertRuleEngine {
+	return &AlertRuleEngine{location: time.UTC}
+}
+
+// ----------------------------------------------------------------------
+
+// SetRules validates and replaces the rule set, resetting all dwell timers
+func (e *AlertRuleEngine) SetRules(ruleSet models.AlertRuleSet) error {
+	location := time.UTC
+	if ruleSet.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(ruleSet.Timezone); err != nil {
+			return invalidParameterError("unknown timezone %q", ruleSet.Timezone)
+		}
+	}
+
+	rules := make([]*compiledAlertRule, 0, len(ruleSet.Rules))
+	for _, rule := range ruleSet.Rules {
+		compiled, err := compileAlertRule(rule)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, compiled)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.ruleSet = ruleSet
+	e.location = location
+	e.rules = rules
+	return nil
+}
+
+// RuleSet returns the current rule set
+func (e *AlertRuleEngine) RuleSet() models.AlertRuleSet {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.ruleSet
+}
+
+// Evaluate returns the detections satisfying at least one firing rule and the highest severity among those rules.
+// A rule fires once it has matched for its dwell time, with gaps shorter than alertRuleMissGrace.
+// Without rules every detection passes.
+func (e *AlertRuleEngine) Evaluate(detections []models.FaceDetection, now time.Time) ([]models.FaceDetection, string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if len(e.rules) == 0 {
+		return detections, ""
+	}
+
+	localNow := now.In(e.location)
+	selected := make([]bool, len(detections))
+	severity := ""
+
+	for _, rule := range e.rules {
+		qualifying := rule.qualifyingDetections(detections)
+
+		minFaceCount := rule.rule.MinFaceCount
+		if minFaceCount < 1 {
+			minFaceCount = 1
+		}
+
+		if !rule.isActive(localNow) {
+			rule.matchingSince = time.Time{}
+			continue
+		}
+		if len(qualifying) < minFaceCount {
+			if now.Sub(rule.lastMatchedAt) >= alertRuleMissGrace {
+				rule.matchingSince = time.Time{}
+			}
+			continue
+		}
+
+		if rule.matchingSince.IsZero() {
+			rule.matchingSince = now
+		}
+		rule.lastMatchedAt = now
+		if now.Sub(rule.matchingSince) < time.Duration(rule.rule.DwellSeconds*float64(time.Second)) {
+			continue
+		}
+
+		for _, index := range qualifying {
+			selected[index] = true
+		}
+		if ruleSeverity := rule.severity(); alertSeverityRank[ruleSeverity] > alertSeverityRank[severity] {
+			severity = ruleSeverity
+		}
+	}
+
+	var matched []models.FaceDetection
+	for index, detection := range detections {
+		if selected[index] {
+			matched = append(matched, detection)
+		}
+	}
+
+	return matched, severity
+}
+
+// ----------------------------------------------------------------------
+
+func compileAlertRule(rule models.AlertRule) (*compiledAlertRule, error) {
+	compiled := &compiledAlertRule{rule: rule}
+
+	for _, window := range rule.ActiveWindows {
+		start, err := parseClockMinutes(window.Start)
+		if err != nil {
+			return nil, invalidParameterError("rule %q: invalid window start %q, expected HH:MM", rule.Name, window.Start)
+		}
+		end, err := parseClockMinutes(window.End)
+		if err != nil {
+			return nil, invalidParameterError("rule %q: invalid window end %q, expected HH:MM", rule.Name, window.End)
+		}
+		compiled.windows = append(compiled.windows, [2]int{start, end})
+	}
+
+	if len(rule.Days) > 0 {
+		compiled.days = make(map[time.Weekday]bool, len(rule.Days))
+		for _, day := range rule.Days {
+			weekday, ok := alertRuleDays[strings.ToLower(day)]
+			if !ok {
+				return nil, invalidParameterError("rule %q: invalid day %q", rule.Name, day)
+			}
+			compiled.days[weekday] = true
+		}
+	}
+
+	return compiled, nil
+}
+
+// qualifyingDetections returns the indexes of detections meeting the rule's confidence and size thresholds
+func (r *compiledAlertRule) qualifyingDetections(detections []models.FaceDetection) []int {
+	var indexes []int
+	for index, detection := range detections {
+		if detection.Confidence < r.rule.MinConfidence {
+			continue
+		}
+		if detection.Width < r.rule.MinFaceSize || detection.Height < r.rule.MinFaceSize {
+			continue
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// isActive reports whether the rule applies at the given local time.
+// A window crossing midnight belongs to the day it starts on, so its part after midnight is
+// checked against the previous day.
+func (r *compiledAlertRule) isActive(localNow time.Time) bool {
+	today := localNow.Weekday()
+	if len(r.windows) == 0 {
+		return r.onDay(today)
+	}
+
+	yesterday := (today + 6) % 7
+	minute := localNow.Hour()*60 + localNow.Minute()
+	for _, window := range r.windows {
+		start, end := window[0], window[1]
+		if start <= end && minute >= start && minute < end && r.onDay(today) {
+			return true
+		}
+		if start > end && minute >= start && r.onDay(today) {
+			return true
+		}
+		if start > end && minute < end && r.onDay(yesterday) {
+			return true
+		}
+	}
+	return false
+}
+
+// onDay reports whether the rule applies on the given weekday
+func (r *compiledAlertRule) onDay(day time.Weekday) bool {
+	return r.days == nil || r.days[day]
+}
+
+func (r *compiledAlertRule) severity() string {
+	if r.rule.Severity == "" {
+		return models.AlertSeverityMedium
+	}
+	return r.rule.Severity
+}
+
+// parseClockMinutes parses HH:MM into minutes since midnight
+func parseClockMinutes(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
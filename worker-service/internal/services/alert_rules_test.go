@@ -0,0 +1,137 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"testing"
+	"time"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+func newTestRuleEngine(t *testing.T, ruleSet models.AlertRuleSet) *AlertRuleEngine {
+	t.Helper()
+
+	engine := NewAlertRuleEngine()
+	if err := engine.SetRules(ruleSet); err != nil {
+		t.Fatalf("SetRules: %v", err)
+	}
+	return engine
+}
+
+func testFace(confidence float32) models.FaceDetection {
+	return models.FaceDetection{X: 10, Y: 10, Width: 40, Height: 40, Confidence: confidence}
+}
+
+// ----------------------------------------------------------------------
+
+func TestAlertRulesWithoutRulesPassEveryDetection(t *testing.T) {
+	engine := NewAlertRuleEngine()
+	detections := []models.FaceDetection{testFace(0.2), testFace(0.9)}
+
+	matched, severity := engine.Evaluate(detections, time.Now())
+	if len(matched) != 2 || severity != "" {
+		t.Errorf("Evaluate = %d detections, severity %q; want every detection and no severity", len(matched), severity)
+	}
+}
+
+func TestAlertRulesThresholdsAndSeverity(t *testing.T) {
+	engine := newTestRuleEngine(t, models.AlertRuleSet{Rules: []models.AlertRule{
+		{Name: "any", MinConfidence: 0.5},
+		{Name: "crowd", MinFaceCount: 2, MinConfidence: 0.8, Severity: models.AlertSeverityHigh},
+	}})
+
+	matched, severity := engine.Evaluate([]models.FaceDetection{testFace(0.9), testFace(0.3)}, time.Now())
+	if len(matched) != 1 || matched[0].Confidence != 0.9 || severity != models.AlertSeverityMedium {
+		t.Errorf("one confident face: %v, %q; want it alone at the default severity", matched, severity)
+	}
+
+	matched, severity = engine.Evaluate([]models.FaceDetection{testFace(0.9), testFace(0.85)}, time.Now())
+	if len(matched) != 2 || severity != models.AlertSeverityHigh {
+		t.Errorf("two confident faces: %d detections, %q; want both at high severity", len(matched), severity)
+	}
+}
+
+func TestAlertRulesDwellSurvivesDetectionFlicker(t *testing.T) {
+	engine := newTestRuleEngine(t, models.AlertRuleSet{Rules: []models.AlertRule{
+		{Name: "loiter", DwellSeconds: 3},
+	}})
+	face := []models.FaceDetection{testFace(0.9)}
+	start := time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)
+
+	// Missed frames shorter than the grace keep the dwell timer running
+	for _, frame := range []struct {
+		offset time.Duration
+		faces  []models.FaceDetection
+		fires  bool
+	}{
+		{0, face, false},
+		{time.Second, nil, false},
+		{1500 * time.Millisecond, face, false},
+		{2 * time.Second, nil, false},
+		{3 * time.Second, face, true},
+	} {
+		matched, _ := engine.Evaluate(frame.faces, start.Add(frame.offset))
+		if fires := len(matched) > 0; fires != frame.fires {
+			t.Errorf("at %v: fires = %v, want %v", frame.offset, fires, frame.fires)
+		}
+	}
+
+	// A gap longer than the grace restarts it
+	restart := start.Add(10 * time.Second)
+	engine.Evaluate(nil, restart.Add(-alertRuleMissGrace-time.Millisecond))
+	if matched, _ := engine.Evaluate(face, restart); len(matched) != 0 {
+		t.Errorf("rule fired right after a gap longer than the grace")
+	}
+	if matched, _ := engine.Evaluate(face, restart.Add(3*time.Second)); len(matched) == 0 {
+		t.Errorf("rule did not fire once the restarted dwell elapsed")
+	}
+}
+
+func TestAlertRulesOvernightWindowBelongsToItsStartDay(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	engine := newTestRuleEngine(t, models.AlertRuleSet{
+		Timezone: "Europe/Berlin",
+		Rules: []models.AlertRule{{
+			Name:          "friday night",
+			Days:          []string{"fri"},
+			ActiveWindows: []models.TimeWindow{{Start: "22:00", End: "06:00"}},
+		}},
+	})
+	face := []models.FaceDetection{testFace(0.9)}
+
+	for _, tc := range []struct {
+		name   string
+		at     time.Time
+		active bool
+	}{
+		{"friday evening", time.Date(2026, time.October, 16, 23, 0, 0, 0, berlin), true},
+		{"saturday early morning", time.Date(2026, time.October, 17, 2, 0, 0, 0, berlin), true},
+		{"saturday at the window end", time.Date(2026, time.October, 17, 6, 0, 0, 0, berlin), false},
+		{"saturday evening", time.Date(2026, time.October, 17, 23, 0, 0, 0, berlin), false},
+		{"friday early morning", time.Date(2026, time.October, 16, 2, 0, 0, 0, berlin), false},
+		{"friday afternoon", time.Date(2026, time.October, 16, 15, 0, 0, 0, berlin), false},
+	} {
+		matched, _ := engine.Evaluate(face, tc.at.UTC())
+		if active := len(matched) > 0; active != tc.active {
+			t.Errorf("%s: active = %v, want %v", tc.name, active, tc.active)
+		}
+	}
+}
+
+func TestAlertRulesRejectInvalidRules(t *testing.T) {
+	for name, ruleSet := range map[string]models.AlertRuleSet{
+		"timezone":     {Timezone: "Mars/Olympus", Rules: []models.AlertRule{{Name: "r"}}},
+		"window start": {Rules: []models.AlertRule{{Name: "r", ActiveWindows: []models.TimeWindow{{Start: "25:00", End: "06:00"}}}}},
+		"day":          {Rules: []models.AlertRule{{Name: "r", Days: []string{"someday"}}}},
+	} {
+		if err := NewAlertRuleEngine().SetRules(ruleSet); err == nil {
+			t.Errorf("%s: SetRules accepted an invalid rule set", name)
+		}
+	}
+}
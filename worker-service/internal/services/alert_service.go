@@ -3,16 +3,16 @@ package services
 // ----------------------------------------------------------------------
 
 import (
-	"bytes"
-	"context"
+	"errors"
 	"fmt"
+	"image"
+	"os"
 	"time"
 	"worker-service/internal/config"
 	"worker-service/internal/models"
 	"worker-service/internal/utils"
 
-	"github.com/cloudinary/cloudinary-go/v2"
-	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+	"github.com/google/uuid"
 	"gocv.io/x/gocv"
 )
 
@@ -20,156 +20,421 @@ import (
 
 // AlertService handles alert creation and snapshot storage
 type AlertService struct {
-	cfg              *config.Config
-	httpClient       *utils.HTTPClient
-	cloudinaryClient *cloudinary.Cloudinary
+	cfg           *config.Config
+	httpClient    *utils.HTTPClient
+	snapshotStore SnapshotStore
+	eventStore    *EventStore
+	outbox        *AlertOutbox
+	sinks         []models.AlertSinkConfig
 }
 
 // NewAlertService creates a new alert service
-func NewAlertService(cfg *config.Config) *AlertService {
-	// Initialize Cloudinary
-	var cld *cloudinary.Cloudinary
-	if cfg.CloudinaryCloudName != "" && cfg.CloudinaryAPIKey != "" && cfg.CloudinaryAPISecret != "" {
-		var err error
-		cld, err = cloudinary.NewFromParams(
-			cfg.CloudinaryCloudName,
-			cfg.CloudinaryAPIKey,
-			cfg.CloudinaryAPISecret,
-		)
+func NewAlertService(cfg *config.Config, eventStore *EventStore) *AlertService {
+	// Initialize the snapshot store, which also stores event clips
+	snapshotStore, err := NewSnapshotStore(cfg)
+	if err != nil {
+		utils.GetLogger().Warnf("⚠️ Snapshot store %s unavailable - snapshots disabled: %v", cfg.SnapshotStore, err)
+	} else {
+		utils.GetLogger().Infof("✓ Snapshots are stored in %s (signed URLs: %v)", cfg.SnapshotStore, cfg.SnapshotSignedURLs)
+	}
+
+	httpClient := utils.NewHTTPClient(cfg.BackendServiceURL, cfg.BackendWorkerAPIKey)
+	httpClient.SetSigningSecrets(cfg.AlertSigningSecrets)
+
+	as := &AlertService{
+		cfg:           cfg,
+		snapshotStore: snapshotStore,
+		httpClient:    httpClient,
+		eventStore:    eventStore,
+		sinks:         []models.AlertSinkConfig{backendSinkConfig},
+	}
+
+	// Global sinks receive the alerts of every camera in addition to the backend
+	if cfg.AlertSinksFile != "" {
+		sinks, err := loadAlertSinks(cfg.AlertSinksFile)
 		if err != nil {
-			utils.GetLogger().Warnf("Failed to initialize Cloudinary: %v", err)
+			utils.GetLogger().Warnf("⚠️ Global alert sinks disabled: %v", err)
 		} else {
-			utils.GetLogger().Info("✓ Cloudinary initialized successfully")
+			as.sinks = append(as.sinks, sinks...)
+			utils.GetLogger().Infof("✓ Loaded %d global alert sinks", len(sinks))
 		}
+	}
+
+	// Persist alerts before delivery so they survive backend and Cloudinary outages
+	outbox, err := NewAlertOutbox(cfg, as.deliverAlert)
+	if err != nil {
+		utils.GetLogger().Warnf("⚠️ Alert outbox disabled - alerts are delivered once without retries: %v", err)
 	} else {
-		utils.GetLogger().Warn("⚠️ Cloudinary credentials not configured - snapshots disabled")
+		outbox.Start()
+		as.outbox = outbox
+		utils.GetLogger().Infof("✓ Alert outbox opened at %s", cfg.AlertOutboxDir)
 	}
 
-	httpClient := utils.NewHTTPClient(cfg.BackendServiceURL, cfg.BackendWorkerAPIKey)
+	return as
+}
+
+// Close stops the outbox sender; undelivered alerts are retried after a restart
+func (as *AlertService) Close() {
+	if as.outbox != nil {
+		as.outbox.Close()
+	}
+}
 
-	return &AlertService{
-		cfg:              cfg,
-		cloudinaryClient: cld,
-		httpClient:       httpClient,
+// OutboxStats returns the alert outbox state, or nil when the outbox is disabled
+func (as *AlertService) OutboxStats() *models.AlertOutboxStats {
+	if as.outbox == nil {
+		return nil
 	}
+	return as.outbox.Stats()
 }
 
 // ----------------------------------------------------------------------
 
-// CreateAlertPayload represents the alert payload sent to backend
+// Backend endpoints: an opened incident creates an alert, later events update it
+const (
+	createAlertEndpoint    = "/api/v1/alerts/create-alert"
+	incidentEventsEndpoint = "/api/v1/alerts/incident-events"
+)
+
+// CreateAlertPayload represents the alert payload sent to backend.
+// SnapshotUrl is the clean frame; the annotated frame and face crops are only present when enabled.
+// Media URLs are signed for each delivery and may expire: receivers must fetch the media rather than store the URLs.
 type CreateAlertPayload struct {
-	CameraID    string                 `json:"cameraId"`
-	FaceCount   int                    `json:"faceCount"`
-	Confidence  float64                `json:"confidence"`
-	SnapshotUrl *string                `json:"snapshotUrl,omitempty"`
-	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	IdempotencyKey       string                 `json:"idempotencyKey"`
+	Event                string                 `json:"event"`
+	IncidentID           string                 `json:"incidentId"`
+	Incident             models.IncidentSummary `json:"incident"`
+	CameraID             string                 `json:"cameraId"`
+	FaceCount            int                    `json:"faceCount"`
+	Confidence           float64                `json:"confidence"`
+	SnapshotUrl          *string                `json:"snapshotUrl,omitempty"`
+	AnnotatedSnapshotUrl *string                `json:"annotatedSnapshotUrl,omitempty"`
+	ClipUrl              *string                `json:"clipUrl,omitempty"`
+	ClipSeconds          *float64               `json:"clipDurationSeconds,omitempty"`
+	Metadata             map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// ProcessDetectionAlert processes face detections and creates alerts
-func (as *AlertService) ProcessDetectionAlert(
+// AlertDetection is a detection of the alert payload with the URL of its face crop
+type AlertDetection struct {
+	models.FaceDetection
+	CropUrl *string `json:"cropUrl,omitempty"`
+}
+
+// ProcessIncidentEvent queues an incident lifecycle event in the outbox, once for the global sinks and
+// every camera sink subscribed to the event. The stored event, if any, is linked to the snapshot once it has been uploaded.
+func (as *AlertService) ProcessIncidentEvent(
 	cameraID string,
 	cameraName string,
-	detections []models.FaceDetection,
-	frame *gocv.Mat,
+	transition *IncidentTransition,
+	clip *EventClip,
+	cameraSinks []models.AlertSinkConfig,
 ) error {
-	if len(detections) == 0 {
-		return nil
+	alert := &outboxAlert{
+		ID:         uuid.New().String(),
+		CameraID:   cameraID,
+		CameraName: cameraName,
+		EventID:    transition.EventID,
+		Event:      transition.Event,
+		Incident:   transition.Incident,
+		Detections: transition.Detections,
+		CreatedAt:  time.Now().UTC(),
 	}
 
-	// Calculate average confidence
-	totalConfidence := float32(0)
-	for _, detection := range detections {
-		totalConfidence += detection.Confidence
+	// Encode the snapshots now; they are uploaded when the alert is delivered
+	if frame := transition.Frame; frame != nil && !frame.Empty() {
+		snapshot, err := encodeSnapshot(frame)
+		if err != nil {
+			utils.GetLogger().Warnf("Failed to encode snapshot for camera %s: %v", cameraID, err)
+		} else {
+			alert.Snapshot = snapshot
+		}
+		alert.FaceCrops = as.encodeFaceCrops(cameraID, frame, transition.Detections)
 	}
-	avgConfidence := float64(totalConfidence) / float64(len(detections))
-
-	// Save snapshot to Cloudinary if frame is provided
-	var snapshotUrl *string
-	if frame != nil && !frame.Empty() {
-		url, err := as.SaveSnapshot(cameraID, frame)
+	if frame := transition.AnnotatedFrame; frame != nil && !frame.Empty() {
+		snapshot, err := encodeSnapshot(frame)
 		if err != nil {
-			utils.GetLogger().Warnf("Failed to save snapshot for camera %s: %v", cameraID, err)
+			utils.GetLogger().Warnf("Failed to encode annotated snapshot for camera %s: %v", cameraID, err)
 		} else {
-			snapshotUrl = &url
+			alert.AnnotatedSnapshot = snapshot
+		}
+	}
+
+	if clip != nil {
+		alert.ClipPath = clip.Path
+		alert.ClipSeconds = clip.Duration.Seconds()
+	}
+
+	sinks := as.sinksFor(cameraID, transition.Event, cameraSinks)
+
+	if as.outbox != nil {
+		err := as.outbox.Enqueue(alert, sinks)
+		if err == nil {
+			utils.GetLogger().Debugf("Alert %s queued for camera %s (%d sinks)", alert.ID, cameraID, len(sinks))
+			return nil
+		}
+		utils.GetLogger().Warnf("Failed to queue alert for camera %s, delivering directly: %v", cameraID, err)
+	}
+
+	var errs []error
+	for _, sink := range sinks {
+		alert.Sink = sink
+		if err := as.deliverAlert(alert, true); err != nil {
+			errs = append(errs, fmt.Errorf("sink %s: %w", sink.Name, err))
+		}
+	}
+	if alert.ClipPath != "" {
+		os.Remove(alert.ClipPath)
+	}
+	return errors.Join(errs...)
+}
+
+// sinksFor returns the global and camera sinks subscribed to an event; camera sinks cannot shadow global ones
+func (as *AlertService) sinksFor(cameraID, event string, cameraSinks []models.AlertSinkConfig) []models.AlertSinkConfig {
+	sinks := make([]models.AlertSinkConfig, 0, len(as.sinks)+len(cameraSinks))
+	names := make(map[string]bool, len(as.sinks))
+
+	for _, sink := range as.sinks {
+		names[sink.Name] = true
+		if sinkAcceptsEvent(sink, event) {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	for _, sink := range cameraSinks {
+		if names[sink.Name] {
+			utils.GetLogger().Warnf("Ignoring alert sink %s of camera %s: a global sink has the same name", sink.Name, cameraID)
+			continue
+		}
+		if sinkAcceptsEvent(sink, event) {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	return sinks
+}
+
+// deliverAlert uploads the alert's media and sends it to the alert's sink.
+// Failed uploads are retried, except on the final attempt where the alert is sent without them.
+func (as *AlertService) deliverAlert(alert *outboxAlert, finalAttempt bool) error {
+	sink, err := as.newAlertSink(alert.Sink)
+	if err != nil {
+		return &permanentDeliveryError{err: fmt.Errorf("invalid alert sink %s: %w", alert.Sink.Name, err)}
+	}
+
+	if alert.SnapshotKey == "" {
+		if err := as.saveAlertImage(alert, "", alert.Snapshot, &alert.SnapshotKey, finalAttempt); err != nil {
+			return err
+		}
+		if alert.SnapshotKey != "" && alert.EventID != "" && as.eventStore != nil {
+			as.eventStore.SetSnapshotKey(alert.EventID, alert.SnapshotKey)
+		}
+	}
+
+	if err := as.saveAlertImage(alert, "annotated", alert.AnnotatedSnapshot, &alert.AnnotatedSnapshotKey, finalAttempt); err != nil {
+		return err
+	}
+
+	if len(alert.FaceCropKeys) < len(alert.FaceCrops) {
+		alert.FaceCropKeys = append(alert.FaceCropKeys, make([]string, len(alert.FaceCrops)-len(alert.FaceCropKeys))...)
+	}
+	for i, crop := range alert.FaceCrops {
+		if err := as.saveAlertImage(alert, fmt.Sprintf("face%d", i+1), crop, &alert.FaceCropKeys[i], finalAttempt); err != nil {
+			return err
+		}
+	}
+
+	if alert.ClipKey == "" && alert.ClipPath != "" && as.snapshotStore != nil {
+		key, err := as.SaveClip(alert.CameraID, &EventClip{Path: alert.ClipPath}, alert.CreatedAt)
+		if err != nil && !finalAttempt {
+			return fmt.Errorf("failed to save clip: %w", err)
+		}
+		if err == nil {
+			alert.ClipKey = key
+		}
+	}
+
+	// Average confidence of the reported frame, or the incident maximum when it carries no frame
+	confidence := float64(alert.Incident.MaxConfidence)
+	if len(alert.Detections) > 0 {
+		totalConfidence := float32(0)
+		for _, detection := range alert.Detections {
+			totalConfidence += detection.Confidence
+		}
+		confidence = float64(totalConfidence) / float64(len(alert.Detections))
+	}
+
+	detections := make([]AlertDetection, len(alert.Detections))
+	for i, detection := range alert.Detections {
+		detections[i] = AlertDetection{FaceDetection: detection}
+		if i < len(alert.FaceCropKeys) {
+			detections[i].CropUrl = as.mediaURL(alert.FaceCropKeys[i])
 		}
 	}
 
 	// Build alert payload
 	payload := CreateAlertPayload{
-		CameraID:    cameraID,
-		FaceCount:   len(detections),
-		Confidence:  avgConfidence,
-		SnapshotUrl: snapshotUrl,
+		IdempotencyKey: alert.ID,
+		Event:          alert.Event,
+		IncidentID:     alert.Incident.ID,
+		Incident:       alert.Incident,
+		CameraID:       alert.CameraID,
+		FaceCount:      alert.Incident.PeakFaceCount,
+		Confidence:     confidence,
 		Metadata: map[string]interface{}{
-			"cameraName":  cameraName,
+			"cameraName":  alert.CameraName,
 			"detections":  detections,
+			"detectedAt":  alert.CreatedAt.Format(time.RFC3339),
 			"processedAt": time.Now().UTC().Format(time.RFC3339),
 		},
 	}
+	payload.SnapshotUrl = as.mediaURL(alert.SnapshotKey)
+	payload.AnnotatedSnapshotUrl = as.mediaURL(alert.AnnotatedSnapshotKey)
+	payload.ClipUrl = as.mediaURL(alert.ClipKey)
+	if payload.ClipUrl != nil {
+		payload.ClipSeconds = &alert.ClipSeconds
+	}
 
-	// Send alert to backend
-	if err := as.SendAlertToBackend(payload); err != nil {
-		return fmt.Errorf("failed to send alert to backend: %w", err)
+	message := &AlertMessage{
+		CreateAlertPayload: payload,
+		CameraName:         alert.CameraName,
+		DetectedAt:         alert.CreatedAt,
+		Snapshot:           alert.Snapshot,
+	}
+	if err := sink.Send(message); err != nil {
+		return err
 	}
 
-	utils.GetLogger().Infof("Alert created successfully for camera %s: %d faces detected (avg confidence: %.2f)",
-		cameraID, len(detections), avgConfidence)
+	utils.GetLogger().Infof("Incident %s %s for camera %s sent to %s: peak %d faces (confidence: %.2f)",
+		alert.Incident.ID, alert.Event, alert.CameraID, alert.Sink.Name, alert.Incident.PeakFaceCount, confidence)
 
 	return nil
 }
 
-// SaveSnapshot uploads the given frame to Cloudinary and returns the URL
-func (as *AlertService) SaveSnapshot(cameraID string, frame *gocv.Mat) (string, error) {
-	if as.cloudinaryClient == nil {
-		return "", fmt.Errorf("cloudinary not configured")
-	}
-
-	// Encode frame to JPEG in memory
+// encodeSnapshot encodes a frame as JPEG
+func encodeSnapshot(frame *gocv.Mat) ([]byte, error) {
 	buf, err := gocv.IMEncode(".jpg", *frame)
 	if err != nil {
-		return "", fmt.Errorf("failed to encode frame: %w", err)
+		return nil, fmt.Errorf("failed to encode frame: %w", err)
 	}
 	defer buf.Close()
 
-	// Generate unique public ID
-	timestamp := time.Now().Format("20060102_150405")
-	publicID := fmt.Sprintf("%s/%s_%s", as.cfg.CloudinaryFolder, cameraID, timestamp)
-
-	// Convert buffer to bytes
-	imageBytes := buf.GetBytes()
-	reader := bytes.NewReader(imageBytes)
-
-	// Upload to Cloudinary
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	uploadResult, err := as.cloudinaryClient.Upload.Upload(
-		ctx,
-		reader,
-		uploader.UploadParams{
-			PublicID:       publicID,
-			ResourceType:   "image",
-			Format:         "jpg",
-			Transformation: "q_auto:low,f_auto",
-		},
-	)
+	// Copy out of the native buffer before it is closed
+	return append([]byte(nil), buf.GetBytes()...), nil
+}
+
+// encodeFaceCrops encodes a padded crop of each detection, up to the configured maximum.
+// Crops are aligned with the detections; a crop that cannot be encoded is left empty.
+func (as *AlertService) encodeFaceCrops(cameraID string, frame *gocv.Mat, detections []models.FaceDetection) [][]byte {
+	count := min(len(detections), as.cfg.AlertMaxFaceCrops)
+	if count == 0 {
+		return nil
+	}
+
+	padding := float64(as.cfg.AlertFaceCropPaddingPercent) / 100
+	bounds := image.Rect(0, 0, frame.Cols(), frame.Rows())
+
+	crops := make([][]byte, count)
+	for i, detection := range detections[:count] {
+		face := paddedFaceRect(detection, padding, bounds)
+		if face.Empty() {
+			continue
+		}
+
+		region := frame.Region(face)
+		crop, err := encodeSnapshot(&region)
+		region.Close()
+		if err != nil {
+			utils.GetLogger().Warnf("Failed to encode face crop for camera %s: %v", cameraID, err)
+			continue
+		}
+		crops[i] = crop
+	}
+
+	return crops
+}
+
+// saveAlertImage stores an image of the alert unless it has been stored already and sets its key.
+// Failures are returned for a retry, except on the final attempt where the alert is sent without the image.
+func (as *AlertService) saveAlertImage(alert *outboxAlert, variant string, image []byte, key *string, finalAttempt bool) error {
+	if *key != "" || len(image) == 0 || as.snapshotStore == nil {
+		return nil
+	}
+
+	saved, err := as.SaveSnapshot(alert.CameraID, variant, image, alert.CreatedAt)
+	if err != nil {
+		if finalAttempt {
+			return nil
+		}
+		if variant == "" {
+			return fmt.Errorf("failed to save snapshot: %w", err)
+		}
+		return fmt.Errorf("failed to save %s snapshot: %w", variant, err)
+	}
+
+	*key = saved
+	return nil
+}
+
+// mediaURL returns the URL of stored media, signed for this delivery, or nil when there is none
+func (as *AlertService) mediaURL(key string) *string {
+	if key == "" || as.snapshotStore == nil {
+		return nil
+	}
+
+	url, err := as.snapshotStore.URL(key)
+	if err != nil {
+		utils.GetLogger().Warnf("Failed to build URL of %s: %v", key, err)
+		return nil
+	}
+	return &url
+}
+
+// SaveSnapshot stores a JPEG snapshot in the configured snapshot store and returns its key.
+// Snapshots are encoded from processed frames, which already carry the camera's privacy masks.
+func (as *AlertService) SaveSnapshot(cameraID, variant string, image []byte, capturedAt time.Time) (string, error) {
+	if as.snapshotStore == nil {
+		return "", fmt.Errorf("snapshot store not configured")
+	}
 
+	key, err := as.snapshotStore.Save(cameraID, variant, image, capturedAt)
 	if err != nil {
-		return "", fmt.Errorf("cloudinary upload failed: %w", err)
+		return "", err
 	}
 
-	utils.GetLogger().Infof("Snapshot uploaded to Cloudinary: %s (size: %d bytes)",
-		uploadResult.SecureURL, len(imageBytes))
+	utils.GetLogger().Infof("Snapshot stored in %s for camera %s (size: %d bytes)", as.cfg.SnapshotStore, cameraID, len(image))
+	return key, nil
+}
 
-	// Return HTTPS URL
-	return uploadResult.SecureURL, nil
+// SnapshotStore returns the configured snapshot store, or nil when snapshots are disabled
+func (as *AlertService) SnapshotStore() SnapshotStore {
+	return as.snapshotStore
+}
+
+// SaveClip stores an event clip in the snapshot store and returns its key; the caller owns the local file
+func (as *AlertService) SaveClip(cameraID string, clip *EventClip, capturedAt time.Time) (string, error) {
+	if as.snapshotStore == nil {
+		return "", fmt.Errorf("snapshot store not configured")
+	}
+
+	key, err := as.snapshotStore.SaveClip(cameraID, clip.Path, capturedAt)
+	if err != nil {
+		return "", err
+	}
+
+	utils.GetLogger().Infof("Clip stored in %s for camera %s", as.cfg.SnapshotStore, cameraID)
+	return key, nil
 }
 
 // SendAlertToBackend sends the alert payload to the backend service
 func (as *AlertService) SendAlertToBackend(payload CreateAlertPayload) error {
-	endpoint := "/api/v1/alerts/create-alert"
+	endpoint := incidentEventsEndpoint
+	if payload.Event == models.IncidentEventOpened {
+		endpoint = createAlertEndpoint
+	}
+
+	headers := map[string]string{"Idempotency-Key": payload.IdempotencyKey}
 
-	_, err := as.httpClient.Post(endpoint, payload)
+	_, err := as.httpClient.PostWithHeaders(endpoint, payload, headers)
 	if err != nil {
 		return fmt.Errorf("failed to send alert to backend: %w", err)
 	}
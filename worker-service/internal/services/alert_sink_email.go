@@ -0,0 +1,252 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	emailDialTimeout     = 10 * time.Second
+	emailSendTimeout     = 60 * time.Second
+	emailBase64LineWidth = 76
+)
+
+const defaultEmailSubject = `VisionGuard alert: {{.CameraName}} incident {{.Event}}`
+
+const defaultEmailBody = `Camera: {{.CameraName}} ({{.CameraID}})
+Event: incident {{.Event}}
+Incident: {{.IncidentID}}
+{{with .Incident.Severity}}Severity: {{.}}
+{{end}}Faces: {{.FaceCount}} (confidence {{printf "%.2f" .Confidence}})
+Detected at: {{.DetectedAt.Format "2006-01-02 15:04:05 MST"}}
+{{with .SnapshotUrl}}Snapshot: {{.}}
+{{end}}{{with .ClipUrl}}Clip: {{.}}
+{{end}}`
+
+// ----------------------------------------------------------------------
+
+// emailSink sends alerts by SMTP with the snapshot attached.
+// The addresses are parsed once: the SMTP envelope takes the bare addresses, the headers keep display names.
+type emailSink struct {
+	cfg     *models.EmailSinkConfig
+	from    *mail.Address
+	to      []*mail.Address
+	subject *template.Template
+	body    *template.Template
+}
+
+func validateEmailSink(cfg *models.EmailSinkConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("email configuration is required")
+	}
+	if cfg.Host == "" || cfg.Port < 1 || cfg.Port > 65535 {
+		return fmt.Errorf("email host and port are required")
+	}
+	if len(cfg.To) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+
+	_, err := newEmailSink(cfg)
+	return err
+}
+
+func newEmailSink(cfg *models.EmailSinkConfig) (*emailSink, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from address: %w", err)
+	}
+
+	to := make([]*mail.Address, len(cfg.To))
+	for i, recipient := range cfg.To {
+		if to[i], err = mail.ParseAddress(recipient); err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", recipient, err)
+		}
+	}
+
+	subject, err := parseSinkTemplate("email subject", cfg.SubjectTemplate, defaultEmailSubject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid subject template: %w", err)
+	}
+
+	body, err := parseSinkTemplate("email body", cfg.BodyTemplate, defaultEmailBody)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+
+	return &emailSink{cfg: cfg, from: from, to: to, subject: subject, body: body}, nil
+}
+
+// Send renders the message and delivers it to every recipient
+func (s *emailSink) Send(message *AlertMessage) error {
+	subject, err := renderSinkTemplate(s.subject, message)
+	if err != nil {
+		return err
+	}
+
+	body, err := renderSinkTemplate(s.body, message)
+	if err != nil {
+		return err
+	}
+
+	data, err := s.compose(message, string(subject), body)
+	if err != nil {
+		return &permanentDeliveryError{err: err}
+	}
+
+	return s.deliver(data)
+}
+
+// compose builds a multipart MIME message with a text part and the snapshot as attachment
+func (s *emailSink) compose(message *AlertMessage, subject string, body []byte) ([]byte, error) {
+	var parts bytes.Buffer
+	writer := multipart.NewWriter(&parts)
+
+	textPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	encoder := quotedprintable.NewWriter(textPart)
+	if _, err := encoder.Write(body); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	if len(message.Snapshot) > 0 {
+		filename := fmt.Sprintf("snapshot_%s_%s.jpg", message.CameraID, message.DetectedAt.Format("20060102_150405"))
+		attachment, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType("image/jpeg", map[string]string{"name": filename})},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": filename})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := attachment.Write(wrapBase64(message.Snapshot)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	// Header values must stay on one line
+	subject = strings.Join(strings.Fields(subject), " ")
+
+	recipients := make([]string, len(s.to))
+	for i, to := range s.to {
+		recipients[i] = to.String()
+	}
+
+	var data bytes.Buffer
+	fmt.Fprintf(&data, "From: %s\r\n", s.from)
+	fmt.Fprintf(&data, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&data, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&data, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&data, "Message-ID: <%s@visionguard>\r\n", message.IdempotencyKey)
+	fmt.Fprintf(&data, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&data, "Content-Type: %s\r\n\r\n", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": writer.Boundary()}))
+	data.Write(parts.Bytes())
+
+	return data.Bytes(), nil
+}
+
+// deliver sends the message over SMTP, using implicit TLS or STARTTLS when the server offers it
+func (s *emailSink) deliver(data []byte) error {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+	dialer := &net.Dialer{Timeout: emailDialTimeout}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.ImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(emailSendTimeout))
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if !s.cfg.ImplicitTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("SMTP STARTTLS failed: %w", err)
+			}
+		}
+	}
+
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(s.from.Address); err != nil {
+		return fmt.Errorf("SMTP sender rejected: %w", err)
+	}
+	for _, to := range s.to {
+		if err := client.Rcpt(to.Address); err != nil {
+			return fmt.Errorf("SMTP recipient %s rejected: %w", to.Address, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP data failed: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return fmt.Errorf("SMTP data failed: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("SMTP data failed: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// wrapBase64 encodes data as base64 split into lines of the MIME maximum length
+func wrapBase64(data []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var wrapped bytes.Buffer
+	for len(encoded) > emailBase64LineWidth {
+		wrapped.WriteString(encoded[:emailBase64LineWidth])
+		wrapped.WriteString("\r\n")
+		encoded = encoded[emailBase64LineWidth:]
+	}
+	wrapped.WriteString(encoded)
+	wrapped.WriteString("\r\n")
+	return wrapped.Bytes()
+}
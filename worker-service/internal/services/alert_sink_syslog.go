@@ -0,0 +1,194 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	syslogTimeout         = 5 * time.Second
+	defaultSyslogNetwork  = "udp"
+	defaultSyslogAppName  = "visionguard"
+	defaultSyslogFacility = "user"
+
+	// syslogStructuredDataID uses the documentation enterprise number of RFC 5612
+	syslogStructuredDataID = "visionguard@32473"
+
+	syslogMaxHostname = 255
+	syslogMaxAppName  = 48
+)
+
+// syslogFacilities maps facility names to their RFC 5424 codes
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogSeverities maps alert severities to RFC 5424 severity codes
+var syslogSeverities = map[string]int{
+	models.AlertSeverityCritical: 2,
+	models.AlertSeverityHigh:     3,
+	models.AlertSeverityMedium:   4,
+	models.AlertSeverityLow:      5,
+}
+
+const (
+	syslogSeverityWarning       = 4
+	syslogSeverityInformational = 6
+)
+
+// ----------------------------------------------------------------------
+
+// syslogSink sends alerts as RFC 5424 messages over UDP, or over TCP with octet-counting framing (RFC 6587)
+type syslogSink struct {
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+}
+
+func validateSyslogSink(cfg *models.SyslogSinkConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("syslog configuration is required")
+	}
+	if cfg.Network != "" && cfg.Network != "udp" && cfg.Network != "tcp" {
+		return fmt.Errorf("syslog network must be udp or tcp")
+	}
+	if _, _, err := net.SplitHostPort(cfg.Address); err != nil {
+		return fmt.Errorf("syslog address must be host:port: %w", err)
+	}
+	if _, ok := syslogFacilities[cfg.Facility]; cfg.Facility != "" && !ok {
+		return fmt.Errorf("unknown syslog facility %q", cfg.Facility)
+	}
+	return nil
+}
+
+func newSyslogSink(cfg *models.SyslogSinkConfig) (*syslogSink, error) {
+	sink := &syslogSink{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		facility: syslogFacilities[defaultSyslogFacility],
+		appName:  syslogHeaderField(cfg.AppName, syslogMaxAppName),
+		hostname: "-",
+	}
+
+	if sink.network == "" {
+		sink.network = defaultSyslogNetwork
+	}
+	if cfg.Facility != "" {
+		sink.facility = syslogFacilities[cfg.Facility]
+	}
+	if sink.appName == "-" {
+		sink.appName = defaultSyslogAppName
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		sink.hostname = syslogHeaderField(hostname, syslogMaxHostname)
+	}
+
+	return sink, nil
+}
+
+// Send writes one syslog message per alert
+func (s *syslogSink) Send(message *AlertMessage) error {
+	line := s.format(message)
+	if s.network == "tcp" {
+		line = strconv.Itoa(len(line)) + " " + line
+	}
+
+	conn, err := net.DialTimeout(s.network, s.address, syslogTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog server %s: %w", s.address, err)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	if _, err := conn.Write([]byte(line)); err != nil {
+		return fmt.Errorf("failed to send syslog message: %w", err)
+	}
+	return nil
+}
+
+// format builds the RFC 5424 message: header, structured data with the alert fields, then a readable text
+func (s *syslogSink) format(message *AlertMessage) string {
+	severity := syslogSeverityWarning
+	if message.Event == models.IncidentEventClosed {
+		severity = syslogSeverityInformational
+	} else if code, ok := syslogSeverities[message.Incident.Severity]; ok {
+		severity = code
+	}
+
+	params := [][2]string{
+		{"cameraId", message.CameraID},
+		{"incidentId", message.IncidentID},
+		{"event", message.Event},
+		{"faceCount", strconv.Itoa(message.FaceCount)},
+		{"confidence", strconv.FormatFloat(message.Confidence, 'f', 2, 64)},
+	}
+	if message.Incident.Severity != "" {
+		params = append(params, [2]string{"severity", message.Incident.Severity})
+	}
+	if message.SnapshotUrl != nil {
+		params = append(params, [2]string{"snapshotUrl", *message.SnapshotUrl})
+	}
+	if message.AnnotatedSnapshotUrl != nil {
+		params = append(params, [2]string{"annotatedSnapshotUrl", *message.AnnotatedSnapshotUrl})
+	}
+	if message.ClipUrl != nil {
+		params = append(params, [2]string{"clipUrl", *message.ClipUrl})
+	}
+
+	var structured strings.Builder
+	structured.WriteString("[" + syslogStructuredDataID)
+	for _, param := range params {
+		fmt.Fprintf(&structured, ` %s="%s"`, param[0], escapeSyslogParam(param[1]))
+	}
+	structured.WriteString("]")
+
+	text := fmt.Sprintf("Incident %s on camera %s: %d faces (confidence %.2f)",
+		message.Event, message.CameraName, message.FaceCount, message.Confidence)
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d incident-%s %s %s",
+		s.facility*8+severity,
+		message.DetectedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname,
+		s.appName,
+		os.Getpid(),
+		message.Event,
+		structured.String(),
+		text,
+	)
+}
+
+// escapeSyslogParam escapes the characters RFC 5424 reserves in parameter values
+func escapeSyslogParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+// syslogHeaderField keeps the printable ASCII of a header field, truncated; empty fields become the nil value "-"
+func syslogHeaderField(value string, maxLength int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+		return r
+	}, value)
+
+	if len(field) > maxLength {
+		field = field[:maxLength]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
@@ -0,0 +1,129 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"text/template"
+	"time"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	defaultWebhookTimeout = 10 * time.Second
+	webhookMaxErrorBody   = 1024
+)
+
+// ----------------------------------------------------------------------
+
+// webhookSink sends alerts to an arbitrary HTTP endpoint, signed when signing secrets are configured
+type webhookSink struct {
+	cfg            *models.WebhookSinkConfig
+	body           *template.Template
+	signingSecrets []string
+	client         *http.Client
+}
+
+func validateWebhookSink(cfg *models.WebhookSinkConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("webhook configuration is required")
+	}
+
+	endpoint, err := url.Parse(cfg.URL)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("webhook url must be an absolute http or https URL")
+	}
+
+	if cfg.BodyTemplate != "" {
+		if _, err := parseSinkTemplate("webhook body", cfg.BodyTemplate, ""); err != nil {
+			return fmt.Errorf("invalid body template: %w", err)
+		}
+	}
+
+	for _, secret := range cfg.SigningSecrets {
+		if len(secret) < utils.MinSigningSecretLength {
+			return fmt.Errorf("webhook signing secrets must be at least %d characters", utils.MinSigningSecretLength)
+		}
+	}
+	return nil
+}
+
+// newWebhookSink creates a webhook sink; its own signing secrets take precedence over the worker's
+func newWebhookSink(cfg *models.WebhookSinkConfig, signingSecrets []string) (*webhookSink, error) {
+	sink := &webhookSink{
+		cfg:            cfg,
+		signingSecrets: signingSecrets,
+		client:         &http.Client{Timeout: defaultWebhookTimeout},
+	}
+	if len(cfg.SigningSecrets) > 0 {
+		sink.signingSecrets = cfg.SigningSecrets
+	}
+	if cfg.TimeoutSeconds > 0 {
+		sink.client.Timeout = time.Duration(cfg.TimeoutSeconds) * time.Second
+	}
+
+	if cfg.BodyTemplate != "" {
+		body, err := parseSinkTemplate("webhook body", cfg.BodyTemplate, "")
+		if err != nil {
+			return nil, err
+		}
+		sink.body = body
+	}
+
+	return sink, nil
+}
+
+// Send posts the rendered body, or the backend JSON payload when no template is configured
+func (s *webhookSink) Send(message *AlertMessage) error {
+	var body []byte
+	var err error
+	if s.body != nil {
+		body, err = renderSinkTemplate(s.body, message)
+	} else {
+		body, err = json.Marshal(message.CreateAlertPayload)
+	}
+	if err != nil {
+		return err
+	}
+
+	method := s.cfg.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	req, err := http.NewRequest(method, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentDeliveryError{err: fmt.Errorf("failed to create webhook request: %w", err)}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", message.IdempotencyKey)
+	for key, value := range s.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+	if len(s.signingSecrets) > 0 {
+		req.Header.Set(utils.SignatureHeader, utils.SignPayload(body, time.Now(), s.signingSecrets))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxErrorBody))
+		return &utils.HTTPStatusError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
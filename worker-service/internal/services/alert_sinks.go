@@ -0,0 +1,242 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"text/template"
+	"time"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+// backendSinkName is the reserved name of the built-in backend sink
+const backendSinkName = "backend"
+
+// backendSinkConfig is the configuration of the built-in backend sink
+var backendSinkConfig = models.AlertSinkConfig{Name: backendSinkName, Type: models.AlertSinkTypeBackend}
+
+// ----------------------------------------------------------------------
+
+// AlertSink delivers alerts to one destination
+type AlertSink interface {
+	Send(message *AlertMessage) error
+}
+
+// AlertMessage is what a sink delivers: the backend payload, plus the details and snapshot
+// that sinks render or attach themselves. It is also the data of sink templates.
+type AlertMessage struct {
+	CreateAlertPayload
+	CameraName string
+	DetectedAt time.Time
+	Snapshot   []byte
+}
+
+// permanentDeliveryError marks a delivery failure that retrying cannot fix, such as a broken template
+type permanentDeliveryError struct {
+	err error
+}
+
+func (e *permanentDeliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentDeliveryError) Unwrap() error {
+	return e.err
+}
+
+// ----------------------------------------------------------------------
+
+// newAlertSink builds the sink described by a validated configuration
+func (as *AlertService) newAlertSink(cfg models.AlertSinkConfig) (AlertSink, error) {
+	switch cfg.Type {
+	case models.AlertSinkTypeBackend:
+		return &backendSink{alertService: as}, nil
+	case models.AlertSinkTypeWebhook:
+		return newWebhookSink(cfg.Webhook, as.cfg.AlertSigningSecrets)
+	case models.AlertSinkTypeEmail:
+		return newEmailSink(cfg.Email)
+	case models.AlertSinkTypeSyslog:
+		return newSyslogSink(cfg.Syslog)
+	default:
+		return nil, fmt.Errorf("unknown alert sink type %q", cfg.Type)
+	}
+}
+
+// backendSink sends alerts to the backend service
+type backendSink struct {
+	alertService *AlertService
+}
+
+func (s *backendSink) Send(message *AlertMessage) error {
+	return s.alertService.SendAlertToBackend(message.CreateAlertPayload)
+}
+
+// ----------------------------------------------------------------------
+
+// validateAlertSinks checks that sink names are unique and each sink has a usable configuration
+func validateAlertSinks(sinks []models.AlertSinkConfig) error {
+	names := make(map[string]bool, len(sinks))
+	for _, sink := range sinks {
+		if sink.Name == "" {
+			return invalidParameterError("alert sink name is required")
+		}
+		if sink.Name == backendSinkName {
+			return invalidParameterError("alert sink name %q is reserved", backendSinkName)
+		}
+		if names[sink.Name] {
+			return invalidParameterError("duplicate alert sink name %q", sink.Name)
+		}
+		names[sink.Name] = true
+
+		for _, event := range sink.Events {
+			if event != models.IncidentEventOpened && event != models.IncidentEventUpdated && event != models.IncidentEventClosed {
+				return invalidParameterError("alert sink %q: unknown event %q", sink.Name, event)
+			}
+		}
+
+		var err error
+		switch sink.Type {
+		case models.AlertSinkTypeWebhook:
+			err = validateWebhookSink(sink.Webhook)
+		case models.AlertSinkTypeEmail:
+			err = validateEmailSink(sink.Email)
+		case models.AlertSinkTypeSyslog:
+			err = validateSyslogSink(sink.Syslog)
+		default:
+			err = fmt.Errorf("type must be %q, %q or %q", models.AlertSinkTypeWebhook, models.AlertSinkTypeEmail, models.AlertSinkTypeSyslog)
+		}
+		if err != nil {
+			return invalidParameterError("alert sink %q: %v", sink.Name, err)
+		}
+	}
+	return nil
+}
+
+// loadAlertSinks reads the global sinks from a JSON file holding an array of sink configurations
+func loadAlertSinks(path string) ([]models.AlertSinkConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert sinks file: %w", err)
+	}
+
+	var sinks []models.AlertSinkConfig
+	if err := json.Unmarshal(data, &sinks); err != nil {
+		return nil, fmt.Errorf("failed to parse alert sinks file: %w", err)
+	}
+
+	if err := validateAlertSinks(sinks); err != nil {
+		return nil, err
+	}
+	return sinks, nil
+}
+
+// sinkAcceptsEvent reports whether a sink subscribes to an incident event
+func sinkAcceptsEvent(sink models.AlertSinkConfig, event string) bool {
+	if len(sink.Events) == 0 {
+		return true
+	}
+	for _, accepted := range sink.Events {
+		if accepted == event {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedSecret replaces credentials in API responses
+const redactedSecret = "********"
+
+// redactAlertSinks returns a copy of the sinks with credentials removed, for API responses
+func redactAlertSinks(sinks []models.AlertSinkConfig) []models.AlertSinkConfig {
+	redacted := make([]models.AlertSinkConfig, len(sinks))
+	for i, sink := range sinks {
+		if sink.Email != nil && sink.Email.Password != "" {
+			email := *sink.Email
+			email.Password = redactedSecret
+			sink.Email = &email
+		}
+		if sink.Webhook != nil && (len(sink.Webhook.SigningSecrets) > 0 || len(sink.Webhook.Headers) > 0) {
+			webhook := *sink.Webhook
+			if len(webhook.SigningSecrets) > 0 {
+				webhook.SigningSecrets = make([]string, len(sink.Webhook.SigningSecrets))
+				for j := range webhook.SigningSecrets {
+					webhook.SigningSecrets[j] = redactedSecret
+				}
+			}
+
+			// Header values carry credentials such as Authorization or API keys; only the names are shown
+			if len(webhook.Headers) > 0 {
+				webhook.Headers = make(map[string]string, len(sink.Webhook.Headers))
+				for name := range sink.Webhook.Headers {
+					webhook.Headers[name] = redactedSecret
+				}
+			}
+			sink.Webhook = &webhook
+		}
+		redacted[i] = sink
+	}
+	return redacted
+}
+
+// ----------------------------------------------------------------------
+
+// parseSinkTemplate parses an optional sink template, falling back to the default text
+func parseSinkTemplate(name, text, defaultText string) (*template.Template, error) {
+	if text == "" {
+		text = defaultText
+	}
+	return template.New(name).Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// renderSinkTemplate executes a sink template; failures are permanent since retrying renders the same
+func renderSinkTemplate(tmpl *template.Template, message *AlertMessage) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, message); err != nil {
+		return nil, &permanentDeliveryError{err: fmt.Errorf("failed to render %s template: %w", tmpl.Name(), err)}
+	}
+	return buf.Bytes(), nil
+}
+
+// ----------------------------------------------------------------------
+
+// CameraAlertSinks holds the alert sinks configured for one camera in addition to the global ones
+type CameraAlertSinks struct {
+	mutex sync.RWMutex
+	sinks []models.AlertSinkConfig
+}
+
+// NewCameraAlertSinks creates an empty per-camera sink set
+func NewCameraAlertSinks() *CameraAlertSinks {
+	return &CameraAlertSinks{}
+}
+
+// SetSinks validates and replaces the camera's sinks
+func (c *CameraAlertSinks) SetSinks(sinks []models.AlertSinkConfig) error {
+	if err := validateAlertSinks(sinks); err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.sinks = append([]models.AlertSinkConfig(nil), sinks...)
+	return nil
+}
+
+// Sinks returns the camera's sinks
+func (c *CameraAlertSinks) Sinks() []models.AlertSinkConfig {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return append([]models.AlertSinkConfig(nil), c.sinks...)
+}
@@ -0,0 +1,220 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	// clipSegmentSeconds matches the output encoder GOP (2 x fps) so every segment starts on a keyframe
+	clipSegmentSeconds = 2
+	clipSegmentPattern = "seg_%03d.ts"
+	clipSegmentGlob    = "seg_*.ts"
+	clipSpareSegments  = 3
+	clipBuildTimeout   = 30 * time.Second
+)
+
+// ----------------------------------------------------------------------
+
+// ClipBuffer keeps a rolling window of encoded MPEG-TS segments on disk and cuts event clips from it
+type ClipBuffer struct {
+	cameraID string
+	dir      string
+	preRoll  time.Duration
+	postRoll time.Duration
+}
+
+// EventClip is an MP4 clip cut around an event
+type EventClip struct {
+	Path     string
+	Duration time.Duration
+}
+
+// NewClipBuffer creates the segment directory for a camera and clears any stale segments
+func NewClipBuffer(cameraID, baseDir string, preRoll, postRoll time.Duration) (*ClipBuffer, error) {
+	dir := filepath.Join(baseDir, fmt.Sprintf("camera_%s", cameraID))
+
+	// The directory is wiped below, so it must be a child of the base directory
+	if !isChildPath(baseDir, dir) {
+		return nil, fmt.Errorf("clip buffer directory %s is outside %s", dir, baseDir)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear clip buffer directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create clip buffer directory: %w", err)
+	}
+
+	return &ClipBuffer{
+		cameraID: cameraID,
+		dir:      dir,
+		preRoll:  preRoll,
+		postRoll: postRoll,
+	}, nil
+}
+
+// isChildPath reports whether path resolves to a directory strictly inside base
+func isChildPath(base, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(base), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ----------------------------------------------------------------------
+
+// TeeOutput returns the FFmpeg tee slave spec that writes the rolling segments
+func (cb *ClipBuffer) TeeOutput() string {
+	options := fmt.Sprintf("segment_time=%d:segment_format=mpegts:segment_wrap=%d:reset_timestamps=1", clipSegmentSeconds, cb.segmentWrap())
+	return segmentTeeOutput(options, filepath.Join(cb.dir, clipSegmentPattern))
+}
+
+// CaptureClip waits for the post-roll to be recorded and writes an MP4 covering the event
+func (cb *ClipBuffer) CaptureClip(eventTime time.Time) (*EventClip, error) {
+	logger := utils.GetLogger()
+	segmentDuration := clipSegmentSeconds * time.Second
+
+	// Wait until the segment containing the end of the post-roll has been closed
+	readyAt := eventTime.Add(cb.postRoll + segmentDuration)
+	time.Sleep(time.Until(readyAt))
+
+	segments, err := cb.segmentsBetween(eventTime.Add(-cb.preRoll), readyAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("no buffered video available for camera %s", cb.cameraID)
+	}
+
+	listFile, err := os.CreateTemp(cb.dir, "concat_*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create concat list: %w", err)
+	}
+	defer os.Remove(listFile.Name())
+
+	for _, segment := range segments {
+		fmt.Fprintf(listFile, "file '%s'\n", segment)
+	}
+	listFile.Close()
+
+	clipPath := filepath.Join(cb.dir, fmt.Sprintf("clip_%s.mp4", eventTime.UTC().Format("20060102_150405.000")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), clipBuildTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-y",
+		"-f", "concat",
+		"-safe", "0",
+		"-i", listFile.Name(),
+		"-c", "copy",
+		"-movflags", "+faststart",
+		clipPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(clipPath)
+		return nil, fmt.Errorf("failed to build clip: %v (%s)", err, strings.TrimSpace(string(output)))
+	}
+
+	duration := probeClipDuration(clipPath)
+	if duration == 0 {
+		duration = time.Duration(len(segments)) * segmentDuration
+	}
+
+	logger.Infof("Event clip created for camera %s: %s (%d segments, %v)",
+		cb.cameraID, clipPath, len(segments), duration.Round(time.Millisecond))
+
+	return &EventClip{Path: clipPath, Duration: duration}, nil
+}
+
+// Close removes the buffered segments
+func (cb *ClipBuffer) Close() {
+	if err := os.RemoveAll(cb.dir); err != nil {
+		utils.GetLogger().Warnf("Failed to remove clip buffer for camera %s: %v", cb.cameraID, err)
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// segmentWrap sizes the on-disk ring so segments outlive the pre-roll plus the post-roll wait
+func (cb *ClipBuffer) segmentWrap() int {
+	window := (cb.preRoll + cb.postRoll).Seconds()
+	return int(math.Ceil(window/clipSegmentSeconds)) + clipSpareSegments
+}
+
+// segmentsBetween returns finished segments overlapping [from, to], ordered oldest first
+func (cb *ClipBuffer) segmentsBetween(from, to time.Time) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(cb.dir, clipSegmentGlob))
+	if err != nil {
+		return nil, err
+	}
+
+	type segment struct {
+		path    string
+		modTime time.Time
+	}
+
+	segments := make([]segment, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{path: path, modTime: info.ModTime()})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].modTime.Before(segments[j].modTime)
+	})
+
+	// The newest segment is still being written
+	if len(segments) > 0 {
+		segments = segments[:len(segments)-1]
+	}
+
+	// A segment's modification time marks its end, so it overlaps the window when it ends after from
+	var selected []string
+	for _, s := range segments {
+		if s.modTime.After(from) && !s.modTime.After(to) {
+			selected = append(selected, s.path)
+		}
+	}
+
+	return selected, nil
+}
+
+// probeClipDuration reads a clip's duration with ffprobe, returning 0 if it cannot be determined
+func probeClipDuration(path string) time.Duration {
+	output, err := exec.Command("ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		path,
+	).Output()
+	if err != nil {
+		return 0
+	}
+
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil {
+		return 0
+	}
+
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -3,7 +3,7 @@ package services
 // ----------------------------------------------------------------------
 
 import (
-	"fmt"
+	"worker-service/internal/models"
 	"worker-service/internal/utils"
 )
 
@@ -27,14 +27,161 @@ func (sm *StreamManager) UpdateFPS(cameraID string, targetFPS int) error {
 	}
 
 	if targetFPS > session.detectedMaxFPS {
-		return fmt.Errorf("target FPS (%d) exceeds camera maximum (%d)", targetFPS, session.detectedMaxFPS)
+		return invalidParameterError("target FPS (%d) exceeds camera maximum (%d)", targetFPS, session.detectedMaxFPS)
 	}
 
 	if targetFPS < 1 {
-		return fmt.Errorf("target FPS must be at least 1")
+		return invalidParameterError("target FPS must be at least 1")
 	}
 
 	utils.GetLogger().Infof("Updating FPS for camera %s: %d -> %d", cameraID, session.targetFPS, targetFPS)
 	session.targetFPS = targetFPS
 	return nil
 }
+
+// UpdateAlertRules replaces the alert rules of a running stream
+func (sm *StreamManager) UpdateAlertRules(cameraID string, ruleSet *models.AlertRuleSet) (*models.AlertRulesResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := session.alertRules.SetRules(*ruleSet); err != nil {
+		return nil, err
+	}
+
+	utils.GetLogger().Infof("Updated alert rules for camera %s: %d rules", cameraID, len(ruleSet.Rules))
+	return &models.AlertRulesResponse{CameraID: cameraID, RuleSet: session.alertRules.RuleSet()}, nil
+}
+
+// UpdateAlertSinks replaces the alert sinks of a running stream; later alerts use the new sinks
+func (sm *StreamManager) UpdateAlertSinks(cameraID string, req *models.UpdateAlertSinksRequest) (*models.AlertSinksResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := session.alertSinks.SetSinks(req.Sinks); err != nil {
+		return nil, err
+	}
+
+	sinks := session.alertSinks.Sinks()
+	utils.GetLogger().Infof("Updated alert sinks for camera %s: %d sinks", cameraID, len(sinks))
+	return &models.AlertSinksResponse{CameraID: cameraID, Sinks: redactAlertSinks(sinks)}, nil
+}
+
+// UpdateZones replaces the detection zones of a running stream
+func (sm *StreamManager) UpdateZones(cameraID string, req *models.UpdateZonesRequest) (*models.ZonesResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := session.zones.SetZones(req.Zones); err != nil {
+		return nil, err
+	}
+
+	zones := session.zones.Zones()
+	session.overlay.SetZones(zones)
+	if req.DrawZones != nil {
+		session.overlay.SetDrawZones(*req.DrawZones)
+	}
+
+	utils.GetLogger().Infof("Updated detection zones for camera %s: %d zones", cameraID, len(zones))
+	return &models.ZonesResponse{
+		CameraID:  cameraID,
+		Zones:     zones,
+		DrawZones: session.overlay.GetConfig().DrawZones,
+	}, nil
+}
+
+// UpdatePrivacyMasks replaces the privacy masks of a running stream
+func (sm *StreamManager) UpdatePrivacyMasks(cameraID string, req *models.UpdatePrivacyMasksRequest) (*models.PrivacyMasksResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateRecordingSource(session.recordingSource, len(req.Masks)); err != nil {
+		return nil, err
+	}
+
+	if err := session.privacyMasks.SetMasks(req.Masks); err != nil {
+		return nil, err
+	}
+
+	masks := session.privacyMasks.Masks()
+	utils.GetLogger().Infof("Updated privacy masks for camera %s: %d masks", cameraID, len(masks))
+	return &models.PrivacyMasksResponse{
+		CameraID: cameraID,
+		Masks:    masks,
+	}, nil
+}
+
+// UpdateAnonymization replaces the face anonymization options of a running stream
+func (sm *StreamManager) UpdateAnonymization(cameraID string, options *models.AnonymizationOptions) (*models.AnonymizationResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := session.anonymizer.SetOptions(*options); err != nil {
+		return nil, err
+	}
+
+	applied := session.anonymizer.Options()
+	utils.GetLogger().Infof("Updated face anonymization for camera %s: enabled=%v, mode=%s, padding=%.2f, unredacted alerts=%v",
+		cameraID, applied.Enabled, applied.Mode, *applied.Padding, applied.KeepUnredactedForAlerts)
+	return &models.AnonymizationResponse{
+		CameraID:      cameraID,
+		Anonymization: applied,
+	}, nil
+}
+
+// UpdateDetectionSettings changes the face detection settings of a running stream; they apply from the next frame
+func (sm *StreamManager) UpdateDetectionSettings(cameraID string, req *models.UpdateDetectionSettingsRequest) (*models.DetectionSettingsResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyDetectionSettings(session, req); err != nil {
+		return nil, err
+	}
+
+	settings := detectionSettings(session)
+	utils.GetLogger().Infof("Updated detection settings for camera %s: confidence=%.2f, input=%dx%d, cooldown=%.1fs, face size=%.2f-%.2f %s",
+		cameraID, settings.MinConfidence, settings.InputWidth, settings.InputHeight, settings.AlertCooldownSeconds,
+		settings.MinFaceSize, settings.MaxFaceSize, settings.FaceSizeUnit)
+	return &models.DetectionSettingsResponse{
+		CameraID: cameraID,
+		Settings: settings,
+	}, nil
+}
+
+// GetOverlay returns the overlay configuration of a running stream
+func (sm *StreamManager) GetOverlay(cameraID string) (*models.OverlayResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OverlayResponse{CameraID: cameraID, Overlay: *session.overlay.GetConfig()}, nil
+}
+
+// UpdateOverlay replaces the overlay configuration of a running stream; it applies from the next frame
+func (sm *StreamManager) UpdateOverlay(cameraID string, config *models.OverlayConfig) (*models.OverlayResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := session.overlay.UpdateConfig(config); err != nil {
+		return nil, err
+	}
+
+	applied := session.overlay.GetConfig()
+	utils.GetLogger().Infof("Updated overlay for camera %s: enabled=%v, boxes=%v, text=%v, fps=%v, line width=%d",
+		cameraID, applied.Enabled, applied.DrawBoundingBox, applied.DrawText, applied.ShowFPS, applied.LineWidth)
+	return &models.OverlayResponse{CameraID: cameraID, Overlay: *applied}, nil
+}
@@ -0,0 +1,120 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"sync"
+	"sync/atomic"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	detectionSubscriberBuffer = 64
+)
+
+// ----------------------------------------------------------------------
+
+// DetectionHub fans out per-frame detection events to live subscribers
+type DetectionHub struct {
+	mutex       sync.RWMutex
+	subscribers map[*DetectionSubscription]struct{}
+}
+
+// DetectionSubscription receives detection events for one camera, or all cameras when cameraID is empty
+type DetectionSubscription struct {
+	cameraID string
+	events   chan models.DetectionEvent
+	dropped  int64
+	closed   bool
+}
+
+// NewDetectionHub creates a new detection hub
+func NewDetectionHub() *DetectionHub {
+	return &DetectionHub{
+		subscribers: make(map[*DetectionSubscription]struct{}),
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// Subscribe registers a new subscriber; an empty cameraID receives every camera
+func (h *DetectionHub) Subscribe(cameraID string) *DetectionSubscription {
+	sub := &DetectionSubscription{
+		cameraID: cameraID,
+		events:   make(chan models.DetectionEvent, detectionSubscriberBuffer),
+	}
+
+	h.mutex.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mutex.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (h *DetectionHub) Unsubscribe(sub *DetectionSubscription) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.closeLocked(sub)
+}
+
+// CloseCamera ends every subscription bound to a single camera, e.g. when its stream stops
+func (h *DetectionHub) CloseCamera(cameraID string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for sub := range h.subscribers {
+		if sub.cameraID == cameraID {
+			h.closeLocked(sub)
+		}
+	}
+}
+
+// HasSubscribers reports whether anyone is listening, so publishers can skip building events
+func (h *DetectionHub) HasSubscribers() bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.subscribers) > 0
+}
+
+// Publish delivers an event without blocking; slow subscribers drop events
+func (h *DetectionHub) Publish(event models.DetectionEvent) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	for sub := range h.subscribers {
+		if sub.cameraID != "" && sub.cameraID != event.CameraID {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			atomic.AddInt64(&sub.dropped, 1)
+		}
+	}
+}
+
+func (h *DetectionHub) closeLocked(sub *DetectionSubscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subscribers, sub)
+	close(sub.events)
+}
+
+// ----------------------------------------------------------------------
+
+// Events returns the channel of detection events; it is closed when the subscription ends
+func (s *DetectionSubscription) Events() <-chan models.DetectionEvent {
+	return s.events
+}
+
+// Dropped returns how many events were dropped because the subscriber fell behind
+func (s *DetectionSubscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
@@ -0,0 +1,29 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+// SubscribeDetections subscribes to the live detection feed of one stream, or of all streams when cameraID is empty
+func (sm *StreamManager) SubscribeDetections(cameraID string) (*DetectionSubscription, error) {
+	if cameraID != "" {
+		if _, err := sm.getSession(cameraID); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := sm.detectionHub.Subscribe(cameraID)
+	utils.GetLogger().Infof("Detection feed subscriber connected (camera: %q)", cameraID)
+	return sub, nil
+}
+
+// UnsubscribeDetections ends a detection feed subscription
+func (sm *StreamManager) UnsubscribeDetections(sub *DetectionSubscription) {
+	sm.detectionHub.Unsubscribe(sub)
+	utils.GetLogger().Infof("Detection feed subscriber disconnected (camera: %q, dropped events: %d)",
+		sub.cameraID, sub.Dropped())
+}
@@ -0,0 +1,169 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"sync"
+	"time"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+// defaultAlertCooldown is the minimum interval between updates of an open incident
+const defaultAlertCooldown = 5 * time.Second
+
+// ----------------------------------------------------------------------
+
+// DetectionTuning holds the live-tunable detection settings of a camera that the face detector does not own:
+// the face size limits and the alert cooldown
+type DetectionTuning struct {
+	mutex         sync.RWMutex
+	minFaceSize   float64
+	maxFaceSize   float64
+	faceSizeUnit  string
+	alertCooldown time.Duration
+}
+
+// NewDetectionTuning creates tuning without face size limits and with the default alert cooldown
+func NewDetectionTuning() *DetectionTuning {
+	return &DetectionTuning{
+		faceSizeUnit:  models.FaceSizeUnitPixels,
+		alertCooldown: defaultAlertCooldown,
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// SetFaceSizeLimits validates and replaces the face size limits; 0 removes a limit
+func (t *DetectionTuning) SetFaceSizeLimits(minSize, maxSize float64, unit string) error {
+	if unit != models.FaceSizeUnitPixels && unit != models.FaceSizeUnitFraction {
+		return invalidParameterError("face size unit must be %q or %q", models.FaceSizeUnitPixels, models.FaceSizeUnitFraction)
+	}
+	if minSize < 0 || maxSize < 0 {
+		return invalidParameterError("face size limits must not be negative")
+	}
+	if unit == models.FaceSizeUnitFraction && (minSize > 1 || maxSize > 1) {
+		return invalidParameterError("fractional face size limits must be at most 1")
+	}
+	if minSize > 0 && maxSize > 0 && minSize > maxSize {
+		return invalidParameterError("minimum face size %.2f exceeds maximum face size %.2f", minSize, maxSize)
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.minFaceSize = minSize
+	t.maxFaceSize = maxSize
+	t.faceSizeUnit = unit
+	return nil
+}
+
+// FaceSizeLimits returns the current face size limits and their unit
+func (t *DetectionTuning) FaceSizeLimits() (minSize, maxSize float64, unit string) {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.minFaceSize, t.maxFaceSize, t.faceSizeUnit
+}
+
+// SetAlertCooldown sets the minimum interval between updates of an open incident
+func (t *DetectionTuning) SetAlertCooldown(cooldown time.Duration) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.alertCooldown = cooldown
+}
+
+// AlertCooldown returns the minimum interval between updates of an open incident
+func (t *DetectionTuning) AlertCooldown() time.Duration {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	return t.alertCooldown
+}
+
+// FilterFaceSizes returns the detections whose box height is within the face size limits; the input is left untouched
+func (t *DetectionTuning) FilterFaceSizes(detections []models.FaceDetection, frameHeight int) []models.FaceDetection {
+	minSize, maxSize, unit := t.FaceSizeLimits()
+	if len(detections) == 0 || (minSize == 0 && maxSize == 0) {
+		return detections
+	}
+
+	if unit == models.FaceSizeUnitFraction {
+		minSize *= float64(frameHeight)
+		maxSize *= float64(frameHeight)
+	}
+
+	kept := make([]models.FaceDetection, 0, len(detections))
+	for _, detection := range detections {
+		height := float64(detection.Height)
+		if (minSize > 0 && height < minSize) || (maxSize > 0 && height > maxSize) {
+			continue
+		}
+		kept = append(kept, detection)
+	}
+	return kept
+}
+
+// ----------------------------------------------------------------------
+
+// applyDetectionSettings changes the detection settings of a session; omitted fields keep their value.
+// Everything is validated before anything is applied.
+func applyDetectionSettings(session *StreamSession, req *models.UpdateDetectionSettingsRequest) error {
+	inputSize := session.faceDetector.GetInputSize()
+	if req.InputWidth != nil {
+		inputSize.X = *req.InputWidth
+	}
+	if req.InputHeight != nil {
+		inputSize.Y = *req.InputHeight
+	}
+	if inputSize.X <= 0 || inputSize.Y <= 0 {
+		return invalidParameterError("invalid detection input size: %dx%d", inputSize.X, inputSize.Y)
+	}
+
+	if req.MinConfidence != nil && (*req.MinConfidence < 0 || *req.MinConfidence > 1) {
+		return invalidParameterError("minimum confidence must be between 0 and 1")
+	}
+	if req.AlertCooldownSeconds != nil && *req.AlertCooldownSeconds < 0 {
+		return invalidParameterError("alert cooldown must not be negative")
+	}
+
+	minSize, maxSize, unit := session.tuning.FaceSizeLimits()
+	if req.MinFaceSize != nil {
+		minSize = *req.MinFaceSize
+	}
+	if req.MaxFaceSize != nil {
+		maxSize = *req.MaxFaceSize
+	}
+	if req.FaceSizeUnit != nil {
+		unit = *req.FaceSizeUnit
+	}
+	if err := session.tuning.SetFaceSizeLimits(minSize, maxSize, unit); err != nil {
+		return err
+	}
+
+	if err := session.faceDetector.SetInputSize(inputSize.X, inputSize.Y); err != nil {
+		return invalidParameterError("%v", err)
+	}
+	if req.MinConfidence != nil {
+		session.faceDetector.SetMinConfidence(*req.MinConfidence)
+	}
+	if req.AlertCooldownSeconds != nil {
+		session.tuning.SetAlertCooldown(time.Duration(*req.AlertCooldownSeconds * float64(time.Second)))
+	}
+	return nil
+}
+
+// detectionSettings returns the detection settings in effect for a session
+func detectionSettings(session *StreamSession) models.DetectionSettings {
+	inputSize := session.faceDetector.GetInputSize()
+	minSize, maxSize, unit := session.tuning.FaceSizeLimits()
+
+	return models.DetectionSettings{
+		MinConfidence:        session.faceDetector.GetMinConfidence(),
+		InputWidth:           inputSize.X,
+		InputHeight:          inputSize.Y,
+		AlertCooldownSeconds: session.tuning.AlertCooldown().Seconds(),
+		MinFaceSize:          minSize,
+		MaxFaceSize:          maxSize,
+		FaceSizeUnit:         unit,
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"image"
+	"sync"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+// ZoneFilter drops detections outside include zones or inside exclude zones and tags the rest with their zones.
+// A detection is located by the center of its bounding box.
+type ZoneFilter struct {
+	mutex sync.RWMutex
+	zones []models.DetectionZone
+}
+
+// NewZoneFilter creates a zone filter with no zones, which keeps every detection
+func NewZoneFilter() *ZoneFilter {
+	return &ZoneFilter{}
+}
+
+// ----------------------------------------------------------------------
+
+// SetZones validates and replaces the zones
+func (f *ZoneFilter) SetZones(zones []models.DetectionZone) error {
+	names := make(map[string]bool, len(zones))
+	for _, zone := range zones {
+		if names[zone.Name] {
+			return invalidParameterError("duplicate zone name %q", zone.Name)
+		}
+		names[zone.Name] = true
+
+		if zone.Type != models.ZoneTypeInclude && zone.Type != models.ZoneTypeExclude {
+			return invalidParameterError("zone %q: type must be %q or %q", zone.Name, models.ZoneTypeInclude, models.ZoneTypeExclude)
+		}
+		if len(zone.Points) < 3 {
+			return invalidParameterError("zone %q: a polygon needs at least 3 points", zone.Name)
+		}
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.zones = append([]models.DetectionZone(nil), zones...)
+	return nil
+}
+
+// Zones returns the current zones
+func (f *ZoneFilter) Zones() []models.DetectionZone {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return append([]models.DetectionZone(nil), f.zones...)
+}
+
+// Apply filters the detections of a frame of the given size and tags each kept detection with its zone names
+func (f *ZoneFilter) Apply(detections []models.FaceDetection, frameWidth, frameHeight int) []models.FaceDetection {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+
+	if len(f.zones) == 0 || len(detections) == 0 || frameWidth == 0 || frameHeight == 0 {
+		return detections
+	}
+
+	hasIncludeZones := false
+	for _, zone := range f.zones {
+		if zone.Type == models.ZoneTypeInclude {
+			hasIncludeZones = true
+			break
+		}
+	}
+
+	kept := make([]models.FaceDetection, 0, len(detections))
+	for _, detection := range detections {
+		x := (float64(detection.X) + float64(detection.Width)/2) / float64(frameWidth)
+		y := (float64(detection.Y) + float64(detection.Height)/2) / float64(frameHeight)
+
+		excluded := false
+		included := !hasIncludeZones
+		var zoneNames []string
+
+		for _, zone := range f.zones {
+			if !pointInPolygon(x, y, zone.Points) {
+				continue
+			}
+
+			zoneNames = append(zoneNames, zone.Name)
+			if zone.Type == models.ZoneTypeExclude {
+				excluded = true
+			} else {
+				included = true
+			}
+		}
+
+		if excluded || !included {
+			continue
+		}
+
+		detection.Zones = zoneNames
+		kept = append(kept, detection)
+	}
+
+	return kept
+}
+
+// ----------------------------------------------------------------------
+
+// pointInPolygon tests a point against a polygon with the even-odd ray casting rule
+func pointInPolygon(x, y float64, polygon []models.NormalizedPoint) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		pi, pj := polygon[i], polygon[j]
+		if (pi.Y > y) != (pj.Y > y) && x < (pj.X-pi.X)*(y-pi.Y)/(pj.Y-pi.Y)+pi.X {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// polygonPixels scales a normalized polygon to pixel coordinates of a frame
+func polygonPixels(points []models.NormalizedPoint, frameWidth, frameHeight int) []image.Point {
+	pixels := make([]image.Point, len(points))
+	for i, point := range points {
+		pixels[i] = image.Pt(int(point.X*float64(frameWidth)), int(point.Y*float64(frameHeight)))
+	}
+	return pixels
+}
@@ -0,0 +1,130 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+// Zones of the tests, on a 1000x1000 frame
+var (
+	// Left half of the frame
+	leftHalf = []models.NormalizedPoint{{X: 0, Y: 0}, {X: 0.5, Y: 0}, {X: 0.5, Y: 1}, {X: 0, Y: 1}}
+
+	// Square in the top left corner of the left half
+	topLeftCorner = []models.NormalizedPoint{{X: 0, Y: 0}, {X: 0.2, Y: 0}, {X: 0.2, Y: 0.2}, {X: 0, Y: 0.2}}
+
+	// U shape open at the top: its notch between x 0.4 and 0.6 reaches down to y 0.6
+	uShape = []models.NormalizedPoint{
+		{X: 0.2, Y: 0.2}, {X: 0.4, Y: 0.2}, {X: 0.4, Y: 0.6}, {X: 0.6, Y: 0.6},
+		{X: 0.6, Y: 0.2}, {X: 0.8, Y: 0.2}, {X: 0.8, Y: 0.8}, {X: 0.2, Y: 0.8},
+	}
+)
+
+// faceAt returns a 20x20 detection centered on a pixel of the 1000x1000 test frame
+func faceAt(x, y int32) models.FaceDetection {
+	return models.FaceDetection{X: x - 10, Y: y - 10, Width: 20, Height: 20}
+}
+
+// ----------------------------------------------------------------------
+
+func TestPointInPolygon(t *testing.T) {
+	triangle := []models.NormalizedPoint{{X: 0.1, Y: 0.1}, {X: 0.9, Y: 0.1}, {X: 0.5, Y: 0.9}}
+
+	for _, tc := range []struct {
+		name    string
+		x, y    float64
+		polygon []models.NormalizedPoint
+		want    bool
+	}{
+		{"square inside", 0.1, 0.1, topLeftCorner, true},
+		{"square outside", 0.3, 0.1, topLeftCorner, false},
+		{"triangle inside", 0.5, 0.5, triangle, true},
+		{"triangle beside the slanted edge", 0.2, 0.6, triangle, false},
+		{"triangle above the apex", 0.5, 0.95, triangle, false},
+		{"concave arm", 0.3, 0.5, uShape, true},
+		{"concave notch", 0.5, 0.4, uShape, false},
+		{"concave base below the notch", 0.5, 0.7, uShape, true},
+		{"concave outside", 0.9, 0.5, uShape, false},
+		{"degenerate polygon", 0.5, 0.5, []models.NormalizedPoint{{X: 0, Y: 0}, {X: 1, Y: 1}}, false},
+	} {
+		if got := pointInPolygon(tc.x, tc.y, tc.polygon); got != tc.want {
+			t.Errorf("%s: pointInPolygon(%v, %v) = %v, want %v", tc.name, tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+func TestZoneFilterWithoutZonesKeepsEveryDetection(t *testing.T) {
+	detections := []models.FaceDetection{faceAt(100, 100), faceAt(900, 900)}
+
+	if kept := NewZoneFilter().Apply(detections, 1000, 1000); len(kept) != 2 || kept[0].Zones != nil {
+		t.Errorf("Apply = %+v, want the detections unchanged", kept)
+	}
+}
+
+func TestZoneFilterApply(t *testing.T) {
+	filter := NewZoneFilter()
+	if err := filter.SetZones([]models.DetectionZone{
+		{Name: "lobby", Type: models.ZoneTypeInclude, Points: leftHalf},
+		{Name: "door", Type: models.ZoneTypeExclude, Points: topLeftCorner},
+		{Name: "desk", Type: models.ZoneTypeInclude, Points: uShape},
+	}); err != nil {
+		t.Fatalf("SetZones: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		face  models.FaceDetection
+		zones []string // nil when the detection is dropped
+	}{
+		{"in one include zone", faceAt(100, 500), []string{"lobby"}},
+		{"in two include zones", faceAt(300, 500), []string{"lobby", "desk"}},
+		{"in the notch of a concave zone", faceAt(500, 400), nil},
+		{"outside every include zone", faceAt(900, 100), nil},
+		{"in an exclude zone inside an include zone", faceAt(100, 100), nil},
+		{"centered in a zone but extending past it", models.FaceDetection{X: 0, Y: 300, Width: 700, Height: 100}, []string{"lobby", "desk"}},
+	} {
+		kept := filter.Apply([]models.FaceDetection{tc.face}, 1000, 1000)
+		switch {
+		case tc.zones == nil && len(kept) != 0:
+			t.Errorf("%s: kept with zones %v, want it dropped", tc.name, kept[0].Zones)
+		case tc.zones != nil && len(kept) != 1:
+			t.Errorf("%s: dropped, want it kept in %v", tc.name, tc.zones)
+		case tc.zones != nil && !slices.Equal(kept[0].Zones, tc.zones):
+			t.Errorf("%s: zones %v, want %v", tc.name, kept[0].Zones, tc.zones)
+		}
+	}
+}
+
+func TestZoneFilterExcludeZonesAloneKeepTheRestOfTheFrame(t *testing.T) {
+	filter := NewZoneFilter()
+	if err := filter.SetZones([]models.DetectionZone{{Name: "door", Type: models.ZoneTypeExclude, Points: topLeftCorner}}); err != nil {
+		t.Fatalf("SetZones: %v", err)
+	}
+
+	kept := filter.Apply([]models.FaceDetection{faceAt(100, 100), faceAt(900, 900)}, 1000, 1000)
+	if len(kept) != 1 || kept[0].X != 890 || kept[0].Zones != nil {
+		t.Errorf("Apply = %+v, want only the detection outside the exclude zone, without zones", kept)
+	}
+}
+
+func TestZoneFilterRejectsInvalidZones(t *testing.T) {
+	for name, zones := range map[string][]models.DetectionZone{
+		"duplicate name": {
+			{Name: "a", Type: models.ZoneTypeInclude, Points: leftHalf},
+			{Name: "a", Type: models.ZoneTypeExclude, Points: topLeftCorner},
+		},
+		"type":       {{Name: "a", Type: "maybe", Points: leftHalf}},
+		"two points": {{Name: "a", Type: models.ZoneTypeInclude, Points: leftHalf[:2]}},
+		"empty zone": {{Name: "a", Type: models.ZoneTypeExclude}},
+	} {
+		if err := NewZoneFilter().SetZones(zones); !errors.Is(err, ErrInvalidParameter) {
+			t.Errorf("%s: SetZones = %v, want an invalid parameter error", name, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"fmt"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+// ServiceError is a typed domain error carrying a stable machine-readable code
+type ServiceError struct {
+	Code    utils.ErrorCode
+	Message string
+	Cause   error
+}
+
+// Sentinel errors for matching with errors.Is
+var (
+	ErrNotFound            = &ServiceError{Code: utils.ErrorCodeNotFound, Message: "not found"}
+	ErrAlreadyActive       = &ServiceError{Code: utils.ErrorCodeAlreadyActive, Message: "already active"}
+	ErrCapacityExceeded    = &ServiceError{Code: utils.ErrorCodeCapacityExceeded, Message: "capacity exceeded"}
+	ErrInvalidParameter    = &ServiceError{Code: utils.ErrorCodeInvalidParameter, Message: "invalid parameter"}
+	ErrForbidden           = &ServiceError{Code: utils.ErrorCodeForbidden, Message: "forbidden"}
+	ErrUpstreamUnavailable = &ServiceError{Code: utils.ErrorCodeUpstreamUnavailable, Message: "upstream unavailable"}
+)
+
+// ----------------------------------------------------------------------
+
+func (e *ServiceError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Cause)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause
+func (e *ServiceError) Unwrap() error {
+	return e.Cause
+}
+
+// ErrorCode returns the machine-readable error code
+func (e *ServiceError) ErrorCode() utils.ErrorCode {
+	return e.Code
+}
+
+// Is matches any ServiceError with the same code
+func (e *ServiceError) Is(target error) bool {
+	t, ok := target.(*ServiceError)
+	return ok && t.Code == e.Code
+}
+
+// ----------------------------------------------------------------------
+
+func notFoundError(format string, args ...interface{}) *ServiceError {
+	return &ServiceError{Code: utils.ErrorCodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+func alreadyActiveError(format string, args ...interface{}) *ServiceError {
+	return &ServiceError{Code: utils.ErrorCodeAlreadyActive, Message: fmt.Sprintf(format, args...)}
+}
+
+func capacityExceededError(format string, args ...interface{}) *ServiceError {
+	return &ServiceError{Code: utils.ErrorCodeCapacityExceeded, Message: fmt.Sprintf(format, args...)}
+}
+
+func invalidParameterError(format string, args ...interface{}) *ServiceError {
+	return &ServiceError{Code: utils.ErrorCodeInvalidParameter, Message: fmt.Sprintf(format, args...)}
+}
+
+func forbiddenError(format string, args ...interface{}) *ServiceError {
+	return &ServiceError{Code: utils.ErrorCodeForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+func upstreamUnavailableError(cause error, format string, args ...interface{}) *ServiceError {
+	return &ServiceError{Code: utils.ErrorCodeUpstreamUnavailable, Message: fmt.Sprintf(format, args...), Cause: cause}
+}
@@ -0,0 +1,57 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+// ListEvents returns a page of stored detection events, newest first
+func (sm *StreamManager) ListEvents(query *models.EventsQuery) (*models.EventsResponse, error) {
+	if sm.eventStore == nil {
+		return nil, notFoundError("event store is disabled")
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, invalidParameterError("to must not be before from")
+	}
+
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultEventsPageLimit
+	}
+
+	events, total, err := sm.eventStore.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if store := sm.alertService.SnapshotStore(); store != nil {
+		for i := range events {
+			if events[i].SnapshotKey == "" {
+				continue
+			}
+			url, err := store.URL(events[i].SnapshotKey)
+			if err != nil {
+				utils.GetLogger().Warnf("Failed to build snapshot URL of event %s: %v", events[i].ID, err)
+				continue
+			}
+			events[i].SnapshotURL = &url
+		}
+	}
+
+	return &models.EventsResponse{
+		Data: events,
+		Pagination: models.Pagination{
+			Page:         query.Page,
+			Limit:        query.Limit,
+			TotalRecords: total,
+			TotalPages:   (total + query.Limit - 1) / query.Limit,
+		},
+	}, nil
+}
@@ -0,0 +1,340 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"worker-service/internal/config"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	eventQueueSize         = 1024
+	eventFlushInterval     = 1 * time.Second
+	eventFlushBatchSize    = 256
+	eventRetentionInterval = 10 * time.Minute
+
+	defaultEventsPageLimit = 50
+)
+
+const eventStoreSchema = `
+CREATE TABLE IF NOT EXISTS detection_events (
+	id             TEXT PRIMARY KEY,
+	camera_id      TEXT    NOT NULL,
+	captured_at    INTEGER NOT NULL,
+	sequence       INTEGER NOT NULL,
+	frame_width    INTEGER NOT NULL,
+	frame_height   INTEGER NOT NULL,
+	face_count     INTEGER NOT NULL,
+	max_confidence REAL    NOT NULL,
+	detections     TEXT    NOT NULL,
+	snapshot_key   TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_detection_events_camera_time ON detection_events (camera_id, captured_at);
+CREATE INDEX IF NOT EXISTS idx_detection_events_time ON detection_events (captured_at);
+`
+
+// ----------------------------------------------------------------------
+
+// EventStore persists every detection batch in an embedded SQLite database.
+// Writes are queued and flushed in batches so frame processing never waits on disk.
+type EventStore struct {
+	db        *sql.DB
+	retention time.Duration
+	queue     chan eventWrite
+	stop      chan struct{}
+	done      sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// eventWrite is a queued insert, or a snapshot update of an event queued before it
+type eventWrite struct {
+	event       models.StoredEvent
+	snapshotKey string
+}
+
+// NewEventStore opens (or creates) the event database
+func NewEventStore(cfg *config.Config) (*EventStore, error) {
+	db, err := openSQLite(cfg.EventStorePath, eventStoreSchema)
+	if err != nil {
+		return nil, fmt.Errorf("event store: %w", err)
+	}
+
+	// Older databases stored snapshot URLs, which may have expired; events keep the snapshot key instead
+	renamed, err := renameSQLiteColumn(db, "detection_events", "snapshot_url", "snapshot_key")
+	if err == nil && renamed {
+		_, err = db.Exec(`UPDATE detection_events SET snapshot_key = NULL`)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("event store: failed to migrate snapshot URLs: %w", err)
+	}
+
+	return &EventStore{
+		db:        db,
+		retention: time.Duration(cfg.EventRetentionHours) * time.Hour,
+		queue:     make(chan eventWrite, eventQueueSize),
+		stop:      make(chan struct{}),
+	}, nil
+}
+
+// ----------------------------------------------------------------------
+
+// Start runs the batch writer and the retention job in the background until Close is called
+func (es *EventStore) Start() {
+	es.done.Add(2)
+	go es.runWriter()
+	go es.runRetention()
+}
+
+// Close flushes queued events and closes the database
+func (es *EventStore) Close() {
+	es.closeOnce.Do(func() {
+		close(es.stop)
+		es.done.Wait()
+
+		if err := es.db.Close(); err != nil {
+			utils.GetLogger().Warnf("[EventStore] Failed to close database: %v", err)
+		}
+	})
+}
+
+// Record queues a detection batch and returns its event ID; the event is dropped if the queue is full
+func (es *EventStore) Record(event models.DetectionEvent) string {
+	stored := models.StoredEvent{
+		ID:          uuid.New().String(),
+		CameraID:    event.CameraID,
+		Sequence:    event.Sequence,
+		CapturedAt:  event.CapturedAt.UTC(),
+		FrameWidth:  event.FrameWidth,
+		FrameHeight: event.FrameHeight,
+		FaceCount:   len(event.Detections),
+		Detections:  event.Detections,
+	}
+	for _, detection := range event.Detections {
+		if detection.Confidence > stored.MaxConfidence {
+			stored.MaxConfidence = detection.Confidence
+		}
+	}
+
+	select {
+	case es.queue <- eventWrite{event: stored}:
+		return stored.ID
+	default:
+		utils.GetLogger().Warnf("[EventStore] Queue full, dropping event for camera %s", event.CameraID)
+		return ""
+	}
+}
+
+// SetSnapshotKey attaches the uploaded snapshot of an alert to its event by its snapshot store key.
+// The update is queued behind the event's insert so it is never applied before the event exists;
+// like events, it is dropped if the queue is full, so alert delivery never waits on disk.
+func (es *EventStore) SetSnapshotKey(eventID, snapshotKey string) {
+	select {
+	case es.queue <- eventWrite{event: models.StoredEvent{ID: eventID}, snapshotKey: snapshotKey}:
+	default:
+		utils.GetLogger().Warnf("[EventStore] Queue full, dropping snapshot of event %s", eventID)
+	}
+}
+
+// Query returns one page of events matching the filters, newest first
+func (es *EventStore) Query(query *models.EventsQuery) ([]models.StoredEvent, int, error) {
+	var conditions []string
+	var args []interface{}
+
+	if query.CameraID != "" {
+		conditions = append(conditions, "camera_id = ?")
+		args = append(args, query.CameraID)
+	}
+	if !query.From.IsZero() {
+		conditions = append(conditions, "captured_at >= ?")
+		args = append(args, query.From.UnixMilli())
+	}
+	if !query.To.IsZero() {
+		conditions = append(conditions, "captured_at <= ?")
+		args = append(args, query.To.UnixMilli())
+	}
+	if query.MinFaces > 0 {
+		conditions = append(conditions, "face_count >= ?")
+		args = append(args, query.MinFaces)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := es.db.QueryRow("SELECT COUNT(*) FROM detection_events"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	rows, err := es.db.Query(
+		"SELECT id, camera_id, captured_at, sequence, frame_width, frame_height, face_count, max_confidence, detections, snapshot_key"+
+			" FROM detection_events"+where+" ORDER BY captured_at DESC, sequence DESC LIMIT ? OFFSET ?",
+		append(args, query.Limit, (query.Page-1)*query.Limit)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.StoredEvent, 0, query.Limit)
+	for rows.Next() {
+		var event models.StoredEvent
+		var capturedAt int64
+		var detections string
+		var snapshotKey sql.NullString
+
+		if err := rows.Scan(&event.ID, &event.CameraID, &capturedAt, &event.Sequence, &event.FrameWidth, &event.FrameHeight,
+			&event.FaceCount, &event.MaxConfidence, &detections, &snapshotKey); err != nil {
+			return nil, 0, fmt.Errorf("failed to read event: %w", err)
+		}
+
+		event.CapturedAt = time.UnixMilli(capturedAt).UTC()
+		if err := json.Unmarshal([]byte(detections), &event.Detections); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode detections of event %s: %w", event.ID, err)
+		}
+		event.SnapshotKey = snapshotKey.String
+
+		events = append(events, event)
+	}
+
+	return events, total, rows.Err()
+}
+
+// PruneBefore deletes events captured before the cutoff and returns how many were removed
+func (es *EventStore) PruneBefore(cutoff time.Time) (int64, error) {
+	result, err := es.db.Exec(`DELETE FROM detection_events WHERE captured_at < ?`, cutoff.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ----------------------------------------------------------------------
+
+// runWriter flushes queued events every interval or once a batch is full
+func (es *EventStore) runWriter() {
+	defer es.done.Done()
+
+	ticker := time.NewTicker(eventFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.StoredEvent, 0, eventFlushBatchSize)
+	for {
+		select {
+		case write := <-es.queue:
+			batch = es.apply(batch, write)
+		case <-ticker.C:
+			batch = es.flush(batch)
+		case <-es.stop:
+			// Drain whatever is still queued
+			for {
+				select {
+				case write := <-es.queue:
+					batch = es.apply(batch, write)
+				default:
+					es.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+// apply adds an insert to the batch, or flushes the batch and applies a snapshot update
+func (es *EventStore) apply(batch []models.StoredEvent, write eventWrite) []models.StoredEvent {
+	if write.snapshotKey == "" {
+		batch = append(batch, write.event)
+		if len(batch) >= eventFlushBatchSize {
+			batch = es.flush(batch)
+		}
+		return batch
+	}
+
+	batch = es.flush(batch)
+	if _, err := es.db.Exec(`UPDATE detection_events SET snapshot_key = ? WHERE id = ?`, write.snapshotKey, write.event.ID); err != nil {
+		utils.GetLogger().Warnf("[EventStore] Failed to attach snapshot to event %s: %v", write.event.ID, err)
+	}
+	return batch
+}
+
+// flush writes a batch in one transaction and returns the emptied batch
+func (es *EventStore) flush(batch []models.StoredEvent) []models.StoredEvent {
+	if len(batch) == 0 {
+		return batch
+	}
+
+	if err := es.insert(batch); err != nil {
+		utils.GetLogger().Errorf("[EventStore] Failed to write %d events: %v", len(batch), err)
+	}
+
+	return batch[:0]
+}
+
+func (es *EventStore) insert(batch []models.StoredEvent) error {
+	tx, err := es.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO detection_events
+		(id, camera_id, captured_at, sequence, frame_width, frame_height, face_count, max_confidence, detections)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, event := range batch {
+		detections, err := json.Marshal(event.Detections)
+		if err != nil {
+			return err
+		}
+
+		if _, err := stmt.Exec(event.ID, event.CameraID, event.CapturedAt.UnixMilli(), event.Sequence,
+			event.FrameWidth, event.FrameHeight, event.FaceCount, event.MaxConfidence, string(detections)); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// runRetention prunes events older than the retention period
+func (es *EventStore) runRetention() {
+	defer es.done.Done()
+
+	if es.retention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(eventRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-es.stop:
+			return
+		case <-ticker.C:
+			removed, err := es.PruneBefore(time.Now().Add(-es.retention))
+			if err != nil {
+				utils.GetLogger().Warnf("[EventStore] Retention failed: %v", err)
+			} else if removed > 0 {
+				utils.GetLogger().Infof("[EventStore] Retention removed %d events", removed)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"image"
+	"sync"
+	"worker-service/internal/models"
+
+	"gocv.io/x/gocv"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	defaultAnonymizationPadding = 0.2
+
+	// anonymizationPixelBlocks is the number of blocks across a pixelated face
+	anonymizationPixelBlocks = 8
+
+	// anonymizationBlurDivisor sets the blur kernel to a third of the face size
+	anonymizationBlurDivisor = 3
+)
+
+// ----------------------------------------------------------------------
+
+// FaceAnonymizer blurs or pixelates detected faces before a frame is stored or published
+type FaceAnonymizer struct {
+	mutex                sync.RWMutex
+	options              models.AnonymizationOptions
+	allowUnredactedAlert bool
+}
+
+// NewFaceAnonymizer creates a disabled face anonymizer.
+// allowUnredactedAlert is the worker-wide permission for cameras to keep unredacted alert snapshots.
+func NewFaceAnonymizer(allowUnredactedAlert bool) *FaceAnonymizer {
+	return &FaceAnonymizer{allowUnredactedAlert: allowUnredactedAlert}
+}
+
+// ----------------------------------------------------------------------
+
+// SetOptions validates and replaces the anonymization options, filling in defaults
+func (a *FaceAnonymizer) SetOptions(options models.AnonymizationOptions) error {
+	if options.Mode == "" {
+		options.Mode = models.AnonymizationModeBlur
+	}
+	if options.Mode != models.AnonymizationModeBlur && options.Mode != models.AnonymizationModePixelate {
+		return invalidParameterError("anonymization mode must be %q or %q", models.AnonymizationModeBlur, models.AnonymizationModePixelate)
+	}
+
+	padding := defaultAnonymizationPadding
+	if options.Padding != nil {
+		padding = *options.Padding
+	}
+	if padding < 0 || padding > 1 {
+		return invalidParameterError("anonymization padding must be between 0 and 1")
+	}
+	options.Padding = &padding
+
+	if options.KeepUnredactedForAlerts && !a.allowUnredactedAlert {
+		return invalidParameterError("unredacted alert snapshots are not permitted on this worker")
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.options = options
+	return nil
+}
+
+// Options returns the current anonymization options
+func (a *FaceAnonymizer) Options() models.AnonymizationOptions {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.options
+}
+
+// KeepsUnredacted reports whether alert snapshots should be taken before faces are redacted
+func (a *FaceAnonymizer) KeepsUnredacted() bool {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return a.options.Enabled && a.options.KeepUnredactedForAlerts
+}
+
+// ActiveMode returns the redaction mode, or an empty string when anonymization is off
+func (a *FaceAnonymizer) ActiveMode() string {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	if !a.options.Enabled {
+		return ""
+	}
+	return a.options.Mode
+}
+
+// Apply redacts every detected face on the frame in place
+func (a *FaceAnonymizer) Apply(frame *gocv.Mat, detections []models.FaceDetection) {
+	a.mutex.RLock()
+	options := a.options
+	a.mutex.RUnlock()
+
+	if !options.Enabled || len(detections) == 0 || frame.Empty() {
+		return
+	}
+
+	bounds := image.Rect(0, 0, frame.Cols(), frame.Rows())
+	for _, detection := range detections {
+		face := paddedFaceRect(detection, *options.Padding, bounds)
+		if face.Empty() {
+			continue
+		}
+
+		region := frame.Region(face)
+		if options.Mode == models.AnonymizationModePixelate {
+			pixelated := pixelate(region, image.Pt(anonymizationPixelBlocks, anonymizationPixelBlocks*face.Dy()/face.Dx()))
+			pixelated.CopyTo(&region)
+			pixelated.Close()
+		} else {
+			kernel := max(face.Dx(), face.Dy())/anonymizationBlurDivisor | 1
+			gocv.GaussianBlur(region, &region, image.Pt(kernel, kernel), 0, 0, gocv.BorderDefault)
+		}
+		region.Close()
+	}
+}
+
+// paddedFaceRect grows a detection box by a fraction of its size on every side, clipped to the frame bounds
+func paddedFaceRect(detection models.FaceDetection, padding float64, bounds image.Rectangle) image.Rectangle {
+	box := image.Rect(int(detection.X), int(detection.Y),
+		int(detection.X+detection.Width), int(detection.Y+detection.Height))
+	pad := image.Pt(int(float64(box.Dx())*padding), int(float64(box.Dy())*padding))
+	return image.Rectangle{Min: box.Min.Sub(pad), Max: box.Max.Add(pad)}.Intersect(bounds)
+}
@@ -0,0 +1,250 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+// Tracker constants
+const (
+	// Minimum overlap between a predicted track box and a detection for them to match
+	trackIoUThreshold = 0.3
+
+	// How long a lost track is kept, predicting its motion, so the face keeps its ID when it reappears
+	trackLostTimeout = 2 * time.Second
+
+	// Kalman filter noise: process noise lets the velocity drift, measurement noise smooths detector jitter
+	trackProcessNoise     = 1.0
+	trackMeasurementNoise = 10.0
+	trackInitialVariance  = 100.0
+)
+
+// ----------------------------------------------------------------------
+
+// FaceTracker follows faces across frames SORT-style: each track predicts its box with constant-velocity
+// Kalman filters, predictions are matched to new detections by IoU, unmatched detections start tracks
+// and tracks that stay unmatched are dropped after trackLostTimeout.
+type FaceTracker struct {
+	mutex  sync.Mutex
+	tracks []*faceTrack
+	nextID int64
+}
+
+// faceTrack is a followed face; the box is filtered as its center, width and height
+type faceTrack struct {
+	id        int64
+	cx        kalman1D
+	cy        kalman1D
+	width     kalman1D
+	height    kalman1D
+	startedAt time.Time
+	lastSeen  time.Time
+	lost      bool
+}
+
+// NewFaceTracker creates a tracker without tracks
+func NewFaceTracker() *FaceTracker {
+	return &FaceTracker{}
+}
+
+// ----------------------------------------------------------------------
+
+// Update matches the detections of a frame to the tracks and returns them labelled with their track.
+// Matching is greedy, highest IoU first.
+func (t *FaceTracker) Update(detections []models.FaceDetection, now time.Time) []models.FaceDetection {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, track := range t.tracks {
+		track.predict()
+	}
+
+	// Candidate pairs above the threshold, best overlap first
+	type candidate struct {
+		track     int
+		detection int
+		iou       float64
+	}
+	var candidates []candidate
+	for i, track := range t.tracks {
+		predicted := track.box()
+		for j, detection := range detections {
+			if iou := boxIoU(predicted, detection); iou >= trackIoUThreshold {
+				candidates = append(candidates, candidate{track: i, detection: j, iou: iou})
+			}
+		}
+	}
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].iou > candidates[b].iou })
+
+	trackMatched := make([]bool, len(t.tracks))
+	detectionTrack := make([]*faceTrack, len(detections))
+	for _, c := range candidates {
+		if trackMatched[c.track] || detectionTrack[c.detection] != nil {
+			continue
+		}
+		trackMatched[c.track] = true
+		detectionTrack[c.detection] = t.tracks[c.track]
+	}
+
+	tracked := make([]models.FaceDetection, len(detections))
+	for i, detection := range detections {
+		track := detectionTrack[i]
+		state := models.TrackStateTracked
+
+		if track == nil {
+			t.nextID++
+			track = newFaceTrack(t.nextID, detection, now)
+			t.tracks = append(t.tracks, track)
+			state = models.TrackStateNew
+		} else {
+			if track.lost {
+				state = models.TrackStateFound
+			}
+			track.correct(detection)
+		}
+		track.lastSeen = now
+		track.lost = false
+
+		detection.ID = fmt.Sprintf("track_%d", track.id)
+		detection.TrackID = track.id
+		detection.TrackState = state
+		detection.TrackAgeSeconds = now.Sub(track.startedAt).Seconds()
+		tracked[i] = detection
+	}
+
+	// Unmatched tracks are lost, and dropped once lost for too long; tracks started above are all matched
+	kept := t.tracks[:0]
+	for i, track := range t.tracks {
+		if i < len(trackMatched) && !trackMatched[i] {
+			track.lost = true
+			if now.Sub(track.lastSeen) >= trackLostTimeout {
+				continue
+			}
+		}
+		kept = append(kept, track)
+	}
+	clear(t.tracks[len(kept):])
+	t.tracks = kept
+
+	return tracked
+}
+
+// Stats returns the number of active and lost tracks, and of tracks started so far
+func (t *FaceTracker) Stats() models.TrackingStats {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	stats := models.TrackingStats{TotalTracks: t.nextID}
+	for _, track := range t.tracks {
+		if track.lost {
+			stats.LostTracks++
+		} else {
+			stats.ActiveTracks++
+		}
+	}
+	return stats
+}
+
+// ----------------------------------------------------------------------
+
+func newFaceTrack(id int64, detection models.FaceDetection, now time.Time) *faceTrack {
+	return &faceTrack{
+		id:        id,
+		cx:        newKalman1D(float64(detection.X) + float64(detection.Width)/2),
+		cy:        newKalman1D(float64(detection.Y) + float64(detection.Height)/2),
+		width:     newKalman1D(float64(detection.Width)),
+		height:    newKalman1D(float64(detection.Height)),
+		startedAt: now,
+	}
+}
+
+// predict advances the track by one processed frame
+func (f *faceTrack) predict() {
+	f.cx.predict()
+	f.cy.predict()
+	f.width.predict()
+	f.height.predict()
+}
+
+// correct updates the track with its matched detection
+func (f *faceTrack) correct(detection models.FaceDetection) {
+	f.cx.correct(float64(detection.X) + float64(detection.Width)/2)
+	f.cy.correct(float64(detection.Y) + float64(detection.Height)/2)
+	f.width.correct(float64(detection.Width))
+	f.height.correct(float64(detection.Height))
+}
+
+// box returns the current estimate of the track box as x, y, width, height
+func (f *faceTrack) box() [4]float64 {
+	width := max(f.width.position, 1)
+	height := max(f.height.position, 1)
+	return [4]float64{f.cx.position - width/2, f.cy.position - height/2, width, height}
+}
+
+// boxIoU returns the intersection over union of a track box and a detection
+func boxIoU(box [4]float64, detection models.FaceDetection) float64 {
+	dx, dy := float64(detection.X), float64(detection.Y)
+	dw, dh := float64(detection.Width), float64(detection.Height)
+
+	overlapW := min(box[0]+box[2], dx+dw) - max(box[0], dx)
+	overlapH := min(box[1]+box[3], dy+dh) - max(box[1], dy)
+	if overlapW <= 0 || overlapH <= 0 {
+		return 0
+	}
+
+	intersection := overlapW * overlapH
+	union := box[2]*box[3] + dw*dh - intersection
+	if union <= 0 {
+		return 0
+	}
+	return intersection / union
+}
+
+// ----------------------------------------------------------------------
+
+// kalman1D is a constant-velocity Kalman filter over one coordinate, with time measured in frames
+type kalman1D struct {
+	position float64
+	velocity float64
+
+	// State covariance [[p00, p01], [p01, p11]]
+	p00, p01, p11 float64
+}
+
+func newKalman1D(position float64) kalman1D {
+	return kalman1D{position: position, p00: trackInitialVariance, p11: trackInitialVariance}
+}
+
+// predict moves the state one frame ahead: x = F x, P = F P F' + Q with F = [[1, 1], [0, 1]]
+func (k *kalman1D) predict() {
+	k.position += k.velocity
+
+	p00 := k.p00 + 2*k.p01 + k.p11
+	p01 := k.p01 + k.p11
+	k.p00 = p00 + trackProcessNoise
+	k.p01 = p01
+	k.p11 += trackProcessNoise
+}
+
+// correct folds in a measured position: the Kalman update with H = [1, 0]
+func (k *kalman1D) correct(measured float64) {
+	innovation := measured - k.position
+	s := k.p00 + trackMeasurementNoise
+	gain0 := k.p00 / s
+	gain1 := k.p01 / s
+
+	k.position += gain0 * innovation
+	k.velocity += gain1 * innovation
+
+	p00 := (1 - gain0) * k.p00
+	p01 := (1 - gain0) * k.p01
+	p11 := k.p11 - gain1*k.p01
+	k.p00, k.p01, k.p11 = p00, p01, p11
+}
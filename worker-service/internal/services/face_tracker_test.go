@@ -0,0 +1,130 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"testing"
+	"time"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+// trackerStart is the time of the first frame in the tracker tests
+var trackerStart = time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)
+
+func testBox(x, y int32) models.FaceDetection {
+	return models.FaceDetection{X: x, Y: y, Width: 100, Height: 100, Confidence: 0.9}
+}
+
+// ----------------------------------------------------------------------
+
+func TestFaceTrackerKeepsIDsOfMovingFaces(t *testing.T) {
+	tracker := NewFaceTracker()
+
+	var firstIDs [2]int64
+	for frame := 0; frame < 20; frame++ {
+		offset := int32(frame * 5)
+		tracked := tracker.Update([]models.FaceDetection{testBox(offset, 0), testBox(400, offset)},
+			trackerStart.Add(time.Duration(frame)*100*time.Millisecond))
+
+		if len(tracked) != 2 {
+			t.Fatalf("frame %d: %d detections, want 2", frame, len(tracked))
+		}
+		for i, detection := range tracked {
+			if frame == 0 {
+				firstIDs[i] = detection.TrackID
+				if detection.TrackState != models.TrackStateNew {
+					t.Errorf("frame 0, face %d: state %q, want %q", i, detection.TrackState, models.TrackStateNew)
+				}
+				continue
+			}
+			if detection.TrackID != firstIDs[i] || detection.TrackState != models.TrackStateTracked {
+				t.Errorf("frame %d, face %d: track %d (%s), want track %d (%s)",
+					frame, i, detection.TrackID, detection.TrackState, firstIDs[i], models.TrackStateTracked)
+			}
+		}
+	}
+
+	if firstIDs[0] == firstIDs[1] {
+		t.Errorf("both faces share track %d", firstIDs[0])
+	}
+	if stats := tracker.Stats(); stats.ActiveTracks != 2 || stats.TotalTracks != 2 {
+		t.Errorf("Stats = %+v, want 2 active tracks of 2", stats)
+	}
+}
+
+func TestFaceTrackerFindsFaceLostWithinTimeout(t *testing.T) {
+	tracker := NewFaceTracker()
+	face := []models.FaceDetection{testBox(100, 100)}
+
+	id := tracker.Update(face, trackerStart)[0].TrackID
+	tracker.Update(nil, trackerStart.Add(trackLostTimeout/2))
+	if stats := tracker.Stats(); stats.LostTracks != 1 || stats.ActiveTracks != 0 {
+		t.Errorf("missed frame: Stats = %+v, want 1 lost track", stats)
+	}
+
+	found := tracker.Update(face, trackerStart.Add(trackLostTimeout-time.Millisecond))[0]
+	if found.TrackID != id || found.TrackState != models.TrackStateFound {
+		t.Errorf("reappeared face: track %d (%s), want track %d (%s)", found.TrackID, found.TrackState, id, models.TrackStateFound)
+	}
+	if found.TrackAgeSeconds < (trackLostTimeout - time.Millisecond).Seconds() {
+		t.Errorf("reappeared face: age %.3fs, want the age of the original track", found.TrackAgeSeconds)
+	}
+}
+
+func TestFaceTrackerDropsFaceLostPastTimeout(t *testing.T) {
+	tracker := NewFaceTracker()
+	face := []models.FaceDetection{testBox(100, 100)}
+
+	id := tracker.Update(face, trackerStart)[0].TrackID
+	tracker.Update(nil, trackerStart.Add(time.Second))
+	tracker.Update(nil, trackerStart.Add(trackLostTimeout))
+	if stats := tracker.Stats(); stats.LostTracks != 0 || stats.ActiveTracks != 0 {
+		t.Errorf("after the timeout: Stats = %+v, want no tracks", stats)
+	}
+
+	again := tracker.Update(face, trackerStart.Add(trackLostTimeout+100*time.Millisecond))[0]
+	if again.TrackID == id || again.TrackState != models.TrackStateNew {
+		t.Errorf("face after the timeout: track %d (%s), want a new track", again.TrackID, again.TrackState)
+	}
+}
+
+func TestFaceTrackerMatchesHighestOverlapFirst(t *testing.T) {
+	tracker := NewFaceTracker()
+
+	first := tracker.Update([]models.FaceDetection{testBox(0, 0), testBox(200, 0)}, trackerStart)
+	left, right := first[0].TrackID, first[1].TrackID
+
+	// Both detections overlap only the right track; the closer one takes it even though it is listed second
+	now := trackerStart.Add(100 * time.Millisecond)
+	tracked := tracker.Update([]models.FaceDetection{testBox(150, 0), testBox(180, 0)}, now)
+
+	if tracked[1].TrackID != right || tracked[1].TrackState != models.TrackStateTracked {
+		t.Errorf("closest detection: track %d (%s), want track %d", tracked[1].TrackID, tracked[1].TrackState, right)
+	}
+	if tracked[0].TrackID == left || tracked[0].TrackID == right || tracked[0].TrackState != models.TrackStateNew {
+		t.Errorf("other detection: track %d (%s), want a new track", tracked[0].TrackID, tracked[0].TrackState)
+	}
+	if stats := tracker.Stats(); stats.ActiveTracks != 2 || stats.LostTracks != 1 {
+		t.Errorf("Stats = %+v, want 2 active tracks and the left one lost", stats)
+	}
+}
+
+func TestBoxIoU(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		box       [4]float64
+		detection models.FaceDetection
+		want      float64
+	}{
+		{"identical", [4]float64{0, 0, 100, 100}, testBox(0, 0), 1},
+		{"half overlap", [4]float64{0, 0, 100, 100}, testBox(50, 0), 50.0 / 150},
+		{"touching", [4]float64{0, 0, 100, 100}, testBox(100, 0), 0},
+		{"apart", [4]float64{0, 0, 100, 100}, testBox(300, 300), 0},
+	} {
+		if got := boxIoU(tc.box, tc.detection); got < tc.want-1e-9 || got > tc.want+1e-9 {
+			t.Errorf("%s: boxIoU = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -4,12 +4,10 @@ package services
 
 import (
 	"bufio"
-	"context"
-	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
-	"strconv"
 	"strings"
 	"time"
 	"worker-service/internal/models"
@@ -19,15 +17,25 @@ import (
 // ----------------------------------------------------------------------
 
 func (sm *StreamManager) startInputFFmpeg(session *StreamSession) error {
-	cmd := exec.Command(
-		"ffmpeg",
+	args := []string{
 		"-rtsp_transport", "tcp",
 		"-i", session.Camera.RTSPUrl,
 		"-c:v", "rawvideo",
 		"-pix_fmt", "bgr24",
 		"-f", "rawvideo",
 		"pipe:1",
-	)
+	}
+
+	// Raw recording copies the camera's own encoding without re-encoding it
+	if session.recordingSource == models.RecordingSourceRaw {
+		args = append(args, "-map", "0:v", "-c:v", "copy", "-f", "tee", sm.recordingManager.TeeOutput(session.CameraID))
+	}
+
+	cmd := exec.Command("ffmpeg", args...)
+	if session.recordingSource != "" {
+		// Segment names are expanded from the local time, so pin it to UTC
+		cmd.Env = append(os.Environ(), "TZ=UTC")
+	}
 
 	stderrPipe, err := cmd.StderrPipe()
 	if err != nil {
@@ -63,9 +71,9 @@ func (sm *StreamManager) startOutputFFmpeg(session *StreamSession) error {
 	}
 
 	mediaPath := fmt.Sprintf("camera_%s", session.CameraID)
+	rtmpURL := fmt.Sprintf("rtmp://%s:%d/%s", sm.config.MediaMTXHost, sm.config.MediaMTXRTMPPort, mediaPath)
 
-	cmd := exec.Command(
-		"ffmpeg",
+	args := []string{
 		"-f", "rawvideo",
 		"-vcodec", "rawvideo",
 		"-pix_fmt", "bgr24",
@@ -82,9 +90,13 @@ func (sm *StreamManager) startOutputFFmpeg(session *StreamSession) error {
 		"-maxrate", "2500k",
 		"-bufsize", "5000k",
 		"-g", fmt.Sprintf("%d", fps*2),
-		"-f", "flv",
-		fmt.Sprintf("rtmp://%s:%d/%s", sm.config.MediaMTXHost, sm.config.MediaMTXRTMPPort, mediaPath),
-	)
+	}
+	args = append(args, sm.outputTargets(rtmpURL, session)...)
+
+	cmd := exec.Command("ffmpeg", args...)
+	if session.recordingSource != "" {
+		cmd.Env = append(os.Environ(), "TZ=UTC")
+	}
 
 	stdin, err := cmd.StdinPipe()
 	if err != nil {
@@ -115,6 +127,30 @@ func (sm *StreamManager) startOutputFFmpeg(session *StreamSession) error {
 	return nil
 }
 
+// outputTargets returns the output arguments, teeing the encoded stream to local sinks when any are enabled
+func (sm *StreamManager) outputTargets(rtmpURL string, session *StreamSession) []string {
+	var localSinks []string
+	if session.clipBuffer != nil {
+		localSinks = append(localSinks, session.clipBuffer.TeeOutput())
+	}
+	if session.recordingSource == models.RecordingSourceProcessed {
+		localSinks = append(localSinks, sm.recordingManager.TeeOutput(session.CameraID))
+	}
+
+	if len(localSinks) == 0 {
+		return []string{"-f", "flv", rtmpURL}
+	}
+
+	outputs := append([]string{"[f=flv]" + rtmpURL}, localSinks...)
+	return []string{"-map", "0:v", "-f", "tee", strings.Join(outputs, "|")}
+}
+
+// segmentTeeOutput returns an FFmpeg tee slave spec writing segments to pattern with the given segment muxer options.
+// The slave uses onfail=ignore, so a failing local sink (e.g. a full disk) never interrupts the published stream.
+func segmentTeeOutput(options, pattern string) string {
+	return fmt.Sprintf("[f=segment:onfail=ignore:%s]%s", options, pattern)
+}
+
 func (sm *StreamManager) monitorFFmpegLogs(pipe io.ReadCloser, cameraID, processType string) {
 	logger := utils.GetLogger()
 
@@ -141,101 +177,6 @@ func (sm *StreamManager) monitorFFmpegProcess(ffmpeg *FFmpegProcess, session *St
 	}()
 }
 
-func (sm *StreamManager) probeStreamInfo(rtspUrl string) (width, height, fps int, err error) {
-	logger := utils.GetLogger()
-	logger.Infof("Probing stream info for: %s", rtspUrl)
-
-	// Add timeout and better error handling
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Use ffprobe with proper JSON output
-	cmd := exec.CommandContext(ctx, "ffprobe",
-		"-v", "quiet",
-		"-print_format", "json",
-		"-show_streams",
-		"-select_streams", "v:0",
-		"-rtsp_transport", "tcp", // Force TCP transport
-		"-analyzeduration", "10M",
-		"-probesize", "10M",
-		rtspUrl,
-	)
-
-	output, err := cmd.Output()
-	if err != nil {
-		logger.Warnf("Failed to probe stream %s: %v", rtspUrl, err)
-		// Return safe defaults
-		return 640, 480, 15, nil
-	}
-
-	// Parse JSON response
-	var result struct {
-		Streams []struct {
-			Width        int    `json:"width"`
-			Height       int    `json:"height"`
-			RFrameRate   string `json:"r_frame_rate"`
-			AvgFrameRate string `json:"avg_frame_rate"`
-			CodecType    string `json:"codec_type"`
-		} `json:"streams"`
-	}
-
-	if err := json.Unmarshal(output, &result); err != nil {
-		logger.Warnf("Failed to parse ffprobe output: %v", err)
-		return 640, 480, 15, nil
-	}
-
-	// Find video stream
-	for _, stream := range result.Streams {
-		if stream.CodecType == "video" {
-			width = stream.Width
-			height = stream.Height
-
-			// Try avg_frame_rate first (more reliable), fall back to r_frame_rate
-			fpsStr := stream.AvgFrameRate
-			if fpsStr == "0/0" || fpsStr == "" {
-				fpsStr = stream.RFrameRate
-			}
-
-			fps = parseFPS(fpsStr)
-
-			// Sanity check FPS (should be between 1-120 for real cameras)
-			if fps <= 0 || fps > 120 {
-				logger.Warnf("Invalid FPS detected (%d), using default 15", fps)
-				fps = 15
-			}
-
-			break
-		}
-	}
-
-	// Validate dimensions
-	if width == 0 || height == 0 {
-		width, height = 640, 480
-	}
-	if fps == 0 {
-		fps = 15
-	}
-
-	logger.Infof("Stream probed: %dx%d @ %dfps", width, height, fps)
-	return width, height, fps, nil
-}
-
-func parseFPS(fpsStr string) int {
-	parts := strings.Split(strings.TrimSpace(fpsStr), "/")
-	if len(parts) == 2 {
-		num, err1 := strconv.Atoi(parts[0])
-		den, err2 := strconv.Atoi(parts[1])
-		if err1 == nil && err2 == nil && den > 0 {
-			calculatedFPS := num / den
-			// Additional sanity check
-			if calculatedFPS > 0 && calculatedFPS <= 120 {
-				return calculatedFPS
-			}
-		}
-	}
-	return 0
-}
-
 func (sm *StreamManager) verifyStreamIsLive(cameraID string, maxAttempts int, retryDelay time.Duration) error {
 	logger := utils.GetLogger()
 	mediaPath := fmt.Sprintf("camera_%s", cameraID)
@@ -5,6 +5,7 @@ package services
 import (
 	"fmt"
 	"io"
+	"sync/atomic"
 	"time"
 	"worker-service/internal/models"
 	"worker-service/internal/utils"
@@ -14,8 +15,18 @@ import (
 
 // ----------------------------------------------------------------------
 
+// fpsMeasureInterval is the window over which the processing rate is measured
+const fpsMeasureInterval = time.Second
+
+// ----------------------------------------------------------------------
+
 type FrameProcessor struct {
 	session *StreamSession
+
+	// Processing rate, measured over fpsMeasureInterval windows by the processing goroutine
+	processingFPS   float64
+	fpsWindowStart  time.Time
+	fpsWindowFrames int
 }
 
 func NewFrameProcessor(session *StreamSession) *FrameProcessor {
@@ -39,6 +50,9 @@ func (fp *FrameProcessor) ProcessFrames() {
 	logger.Infof("📊 FPS control: %d/%d fps → skip ratio: %d (process every %d frames)",
 		fp.session.targetFPS, fp.session.detectedMaxFPS, skipRatio, skipRatio)
 
+	// An incident cannot outlive the frames that feed it, whether the stream stopped, ended or failed
+	defer func() { fp.session.closeIncident(time.Now()) }()
+
 	for {
 		select {
 		case <-fp.session.Stop:
@@ -77,6 +91,7 @@ func (fp *FrameProcessor) processSingleFrame(frameBuffer []byte, consecutiveErro
 	if err != nil {
 		return fp.handleReadError(err, consecutiveErrors)
 	}
+	capturedAt := time.Now()
 
 	if n != len(frameBuffer) {
 		return fmt.Errorf("incomplete frame read")
@@ -94,6 +109,7 @@ func (fp *FrameProcessor) processSingleFrame(frameBuffer []byte, consecutiveErro
 
 	// Increment processed frames
 	fp.session.IncrementFramesProcessed()
+	fp.measureFPS(capturedAt)
 
 	// Log metrics every 10 seconds
 	if time.Since(fp.session.lastMetricsLog) >= 10*time.Second {
@@ -101,10 +117,10 @@ func (fp *FrameProcessor) processSingleFrame(frameBuffer []byte, consecutiveErro
 		fp.session.lastMetricsLog = time.Now()
 	}
 
-	return fp.processFrameData(frameBuffer)
+	return fp.processFrameData(frameBuffer, capturedAt)
 }
 
-func (fp *FrameProcessor) processFrameData(frameBuffer []byte) error {
+func (fp *FrameProcessor) processFrameData(frameBuffer []byte, capturedAt time.Time) error {
 	mat, err := gocv.NewMatFromBytes(fp.session.detectedHeight, fp.session.detectedWidth, gocv.MatTypeCV8UC3, frameBuffer)
 	if err != nil || mat.Empty() {
 		if !mat.Empty() {
@@ -114,9 +130,53 @@ func (fp *FrameProcessor) processFrameData(frameBuffer []byte) error {
 	}
 	defer mat.Close()
 
-	detections := fp.detectFaces(mat)
-	fp.applyOverlay(mat, detections)
-	fp.handleAlerts(detections, mat)
+	// The frame shares frameBuffer, so masking first keeps masked areas out of every consumer below
+	fp.session.privacyMasks.Apply(&mat)
+
+	// Size limits and zones only decide what alerts and is reported; every detected face is redacted
+	detected := fp.detectFaces(mat)
+	detections := fp.session.tuning.FilterFaceSizes(detected, fp.session.detectedHeight)
+	detections = fp.session.zones.Apply(detections, fp.session.detectedWidth, fp.session.detectedHeight)
+	detections = fp.session.tracker.Update(detections, capturedAt)
+
+	// Rules are evaluated up front so that only frames which alert are copied for snapshots
+	now := time.Now()
+	alerting, severity := fp.evaluateAlertRules(detections, now)
+
+	// Redact faces before the frame is stored or published; alerts may keep an unredacted copy
+	alertFrame := mat
+	keepUnredacted := fp.session.anonymizer.KeepsUnredacted() && len(alerting) > 0
+	if keepUnredacted {
+		alertFrame = mat.Clone()
+		defer alertFrame.Close()
+	}
+	fp.session.anonymizer.Apply(&mat, detected)
+
+	fp.session.latestFrame.StoreRaw(frameBuffer, capturedAt)
+
+	sequence := atomic.AddInt64(&fp.session.frameSequence, 1)
+	fp.publishDetections(detections, sequence, capturedAt)
+	eventID := fp.recordDetections(detections, sequence, capturedAt)
+
+	// The clean snapshot must not carry the annotations drawn into the frame below, only the logo and watermark
+	if len(alerting) > 0 && fp.overlayEnabled() {
+		if !keepUnredacted {
+			alertFrame = mat.Clone()
+			defer alertFrame.Close()
+		}
+		fp.session.overlay.RenderImageOverlays(&alertFrame)
+	}
+
+	var annotatedFrame *gocv.Mat
+	if fp.applyOverlay(mat, detections, sequence) {
+		fp.storeAnnotatedFrame(mat)
+		if fp.session.annotatedSnapshots {
+			annotatedFrame = &mat
+		}
+	} else if !fp.overlayEnabled() {
+		fp.session.latestFrame.ClearAnnotated()
+	}
+	fp.handleAlerts(alerting, severity, alertFrame, annotatedFrame, eventID, now)
 
 	return fp.writeOutputFrame(mat)
 }
@@ -130,40 +190,119 @@ func (fp *FrameProcessor) detectFaces(mat gocv.Mat) []models.FaceDetection {
 	return detections
 }
 
-func (fp *FrameProcessor) applyOverlay(mat gocv.Mat, detections []models.FaceDetection) {
-	if fp.session.overlay == nil || !fp.session.overlay.IsEnabled() {
+// publishDetections pushes the frame's detections to live metadata subscribers
+func (fp *FrameProcessor) publishDetections(detections []models.FaceDetection, sequence int64, capturedAt time.Time) {
+	hub := fp.session.detectionHub
+	if hub == nil || !hub.HasSubscribers() {
 		return
 	}
 
-	fp.session.overlay.RenderDetections(
+	if detections == nil {
+		detections = []models.FaceDetection{}
+	}
+
+	hub.Publish(models.DetectionEvent{
+		CameraID:    fp.session.CameraID,
+		Sequence:    sequence,
+		CapturedAt:  capturedAt,
+		FrameWidth:  fp.session.detectedWidth,
+		FrameHeight: fp.session.detectedHeight,
+		Detections:  detections,
+	})
+}
+
+// recordDetections persists a frame with faces in the event store and returns the event ID
+func (fp *FrameProcessor) recordDetections(detections []models.FaceDetection, sequence int64, capturedAt time.Time) string {
+	if fp.session.eventStore == nil || len(detections) == 0 {
+		return ""
+	}
+
+	return fp.session.eventStore.Record(models.DetectionEvent{
+		CameraID:    fp.session.CameraID,
+		Sequence:    sequence,
+		CapturedAt:  capturedAt,
+		FrameWidth:  fp.session.detectedWidth,
+		FrameHeight: fp.session.detectedHeight,
+		Detections:  detections,
+	})
+}
+
+func (fp *FrameProcessor) overlayEnabled() bool {
+	return fp.session.overlay != nil && fp.session.overlay.IsEnabled()
+}
+
+// applyOverlay draws the overlay on the frame and reports whether anything was drawn
+func (fp *FrameProcessor) applyOverlay(mat gocv.Mat, detections []models.FaceDetection, sequence int64) bool {
+	if !fp.overlayEnabled() {
+		return false
+	}
+
+	err := fp.session.overlay.RenderDetections(
 		&mat,
 		detections,
 		fp.session.Camera.Name,
 		fp.session.Camera.Location,
-		0, 0,
+		fp.processingFPS,
+		sequence,
 	)
+	return err == nil
 }
 
-func (fp *FrameProcessor) handleAlerts(detections []models.FaceDetection, mat gocv.Mat) {
-	if len(detections) > 0 && fp.session.alertService != nil {
-		now := time.Now()
-		if now.Sub(fp.session.lastAlertTime) >= fp.session.alertCooldown {
-			go fp.createAlertAsync(detections, mat.Clone())
-			fp.session.lastAlertTime = now
-		}
+// measureFPS counts a processed frame and refreshes the processing rate at the end of each window
+func (fp *FrameProcessor) measureFPS(now time.Time) {
+	if fp.fpsWindowStart.IsZero() {
+		fp.fpsWindowStart = now
+	}
+	fp.fpsWindowFrames++
+
+	if elapsed := now.Sub(fp.fpsWindowStart); elapsed >= fpsMeasureInterval {
+		fp.processingFPS = float64(fp.fpsWindowFrames) / elapsed.Seconds()
+		fp.fpsWindowStart = now
+		fp.fpsWindowFrames = 0
 	}
 }
 
-func (fp *FrameProcessor) createAlertAsync(detections []models.FaceDetection, frameCopy gocv.Mat) {
-	defer frameCopy.Close()
+// storeAnnotatedFrame keeps a copy of the overlaid frame for snapshots
+func (fp *FrameProcessor) storeAnnotatedFrame(mat gocv.Mat) {
+	data, err := mat.DataPtrUint8()
+	if err != nil {
+		return
+	}
+	fp.session.latestFrame.StoreAnnotated(data)
+}
 
-	if err := fp.session.alertService.ProcessDetectionAlert(
-		fp.session.CameraID,
-		fp.session.Camera.Name,
-		detections,
-		&frameCopy,
-	); err != nil {
-		utils.GetLogger().Errorf("Failed to process alert for camera %s: %v", fp.session.CameraID, err)
+// evaluateAlertRules returns the detections that alert under the camera's rules and their severity
+func (fp *FrameProcessor) evaluateAlertRules(detections []models.FaceDetection, now time.Time) ([]models.FaceDetection, string) {
+	if fp.session.alertService == nil || fp.session.incidents == nil {
+		return nil, ""
+	}
+	return fp.session.alertRules.Evaluate(detections, now)
+}
+
+// handleAlerts feeds the alerting detections to the incident tracker, with the clean frame and, if kept, the annotated one.
+// The alert cooldown is the minimum interval between updates of an open incident.
+func (fp *FrameProcessor) handleAlerts(alerting []models.FaceDetection, severity string, mat gocv.Mat, annotated *gocv.Mat, eventID string, now time.Time) {
+	if fp.session.alertService == nil || fp.session.incidents == nil {
+		return
+	}
+
+	transition := fp.session.incidents.Observe(alerting, severity, mat, annotated, now, fp.session.tuning.AlertCooldown(), eventID)
+	if transition == nil {
+		return
+	}
+
+	if transition.Event == models.IncidentEventOpened && fp.session.ptzClient != nil && fp.session.alertPresetToken != "" {
+		go fp.recallAlertPreset()
+	}
+
+	fp.session.incidentReporter.Report(transition, now)
+}
+
+// recallAlertPreset moves a PTZ camera to its configured alert preset
+func (fp *FrameProcessor) recallAlertPreset() {
+	if err := fp.session.ptzClient.GotoPreset(fp.session.alertPresetToken, nil); err != nil {
+		utils.GetLogger().Warnf("Failed to recall alert preset %s for camera %s: %v",
+			fp.session.alertPresetToken, fp.session.CameraID, err)
 	}
 }
 
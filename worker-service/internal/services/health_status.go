@@ -4,6 +4,7 @@ package services
 
 import (
 	"fmt"
+	"sync/atomic"
 	"time"
 	"worker-service/internal/models"
 )
@@ -51,6 +52,13 @@ func (sm *StreamManager) GetStreamStatus(cameraID string) (*models.StreamStatusR
 		DropRate:        dropRate,
 		TargetFPS:       session.targetFPS,
 		DetectedFPS:     session.detectedMaxFPS,
+		PTZEnabled:      session.ptzClient != nil,
+		MJPEGClients:    int(atomic.LoadInt32(&session.mjpegClients)),
+		Recording:       session.recordingSource,
+		Anonymization:   session.anonymizer.ActiveMode(),
+
+		DetectionSettings: detectionSettings(session),
+		Tracking:          session.tracker.Stats(),
 	}, nil
 }
 
@@ -118,5 +126,6 @@ func (sm *StreamManager) GetHealthStatus() *models.HealthCheckResponse {
 		UtilizationPercent:    utilizationPercent,
 		StreamSessions:        streamStatuses,
 		StreamDetails:         streamDetails,
+		AlertOutbox:           sm.alertService.OutboxStats(),
 	}
 }
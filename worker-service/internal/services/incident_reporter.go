@@ -0,0 +1,110 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"sync"
+	"time"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	// Transitions waiting while an opened incident waits for its clip; updates come at most once per alert cooldown
+	incidentReportBuffer = 16
+)
+
+// ----------------------------------------------------------------------
+
+// IncidentReporter queues the incident transitions of one session in the order they happened.
+// A single goroutine reports them, so an opened incident waiting for its clip holds back its later
+// events instead of being overtaken by them. It owns the session's clip buffer, which is removed once
+// every pending clip has been built.
+type IncidentReporter struct {
+	session *StreamSession
+
+	mutex   sync.Mutex
+	closed  bool
+	reports chan incidentReport
+}
+
+// incidentReport is a transition together with the time of the frame that caused it
+type incidentReport struct {
+	transition *IncidentTransition
+	eventTime  time.Time
+}
+
+// NewIncidentReporter creates the reporter of a session and starts its goroutine
+func NewIncidentReporter(session *StreamSession) *IncidentReporter {
+	r := &IncidentReporter{
+		session: session,
+		reports: make(chan incidentReport, incidentReportBuffer),
+	}
+	go r.run()
+	return r
+}
+
+// ----------------------------------------------------------------------
+
+// Report queues a transition; the reporter owns its frames from then on.
+// Transitions reported after Close are dropped.
+func (r *IncidentReporter) Report(transition *IncidentTransition, eventTime time.Time) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.closed {
+		transition.CloseFrames()
+		return
+	}
+	r.reports <- incidentReport{transition: transition, eventTime: eventTime}
+}
+
+// Close stops accepting transitions; the queued ones are still reported in the background
+func (r *IncidentReporter) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.closed {
+		r.closed = true
+		close(r.reports)
+	}
+}
+
+// ----------------------------------------------------------------------
+
+func (r *IncidentReporter) run() {
+	for report := range r.reports {
+		r.report(report.transition, report.eventTime)
+	}
+
+	if r.session.clipBuffer != nil {
+		r.session.clipBuffer.Close()
+	}
+}
+
+func (r *IncidentReporter) report(transition *IncidentTransition, eventTime time.Time) {
+	defer transition.CloseFrames()
+	session := r.session
+
+	// Wait for the post-event footage so the clip can be attached to the new incident
+	var clip *EventClip
+	if transition.Event == models.IncidentEventOpened && session.clipBuffer != nil {
+		var err error
+		if clip, err = session.clipBuffer.CaptureClip(eventTime); err != nil {
+			utils.GetLogger().Warnf("Failed to capture event clip for camera %s: %v", session.CameraID, err)
+		}
+	}
+
+	if err := session.alertService.ProcessIncidentEvent(
+		session.CameraID,
+		session.Camera.Name,
+		transition,
+		clip,
+		session.alertSinks.Sinks(),
+	); err != nil {
+		utils.GetLogger().Errorf("Failed to report incident %s for camera %s: %v",
+			transition.Event, session.CameraID, err)
+	}
+}
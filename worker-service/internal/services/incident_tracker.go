@@ -0,0 +1,244 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"sync"
+	"time"
+	"worker-service/internal/models"
+
+	"github.com/google/uuid"
+	"gocv.io/x/gocv"
+)
+
+// ----------------------------------------------------------------------
+
+// IncidentTracker aggregates consecutive detections of one camera into incidents.
+// An incident opens when faces appear, is updated while they persist and closes after a quiet period.
+type IncidentTracker struct {
+	mutex       sync.Mutex
+	quietPeriod time.Duration
+	current     *trackedIncident
+}
+
+// trackedIncident is the open incident together with its best frame not yet sent to the backend
+type trackedIncident struct {
+	summary        models.IncidentSummary
+	lastUpdateAt   time.Time
+	bestScore      float32
+	pending        bool
+	bestFrame      *gocv.Mat
+	bestAnnotated  *gocv.Mat
+	bestDetections []models.FaceDetection
+	bestEventID    string
+	trackIDs       map[int64]struct{}
+}
+
+// IncidentTransition is a lifecycle event to report; the receiver owns its frames and must close them.
+// Frame is the clean frame, AnnotatedFrame the same frame with the overlay drawn, when one was kept.
+type IncidentTransition struct {
+	Event          string
+	Incident       models.IncidentSummary
+	Detections     []models.FaceDetection
+	Frame          *gocv.Mat
+	AnnotatedFrame *gocv.Mat
+	EventID        string
+}
+
+// NewIncidentTracker creates a new incident tracker
+func NewIncidentTracker(quietPeriod time.Duration) *IncidentTracker {
+	return &IncidentTracker{quietPeriod: quietPeriod}
+}
+
+// ----------------------------------------------------------------------
+
+// Observe feeds the alerting detections of a processed frame and returns the transition to report, if any.
+// annotated is the frame with the overlay, or nil when no annotated snapshot is wanted.
+// Updates are sent only when the incident improved or escalated, and at most once per updateInterval.
+func (t *IncidentTracker) Observe(detections []models.FaceDetection, severity string, frame gocv.Mat, annotated *gocv.Mat, now time.Time, updateInterval time.Duration, eventID string) *IncidentTransition {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if len(detections) == 0 {
+		if t.current != nil && now.Sub(t.current.summary.LastSeenAt) >= t.quietPeriod {
+			return t.closeLocked(t.current.summary.LastSeenAt)
+		}
+		return nil
+	}
+
+	score := incidentScore(detections)
+	maxConfidence := maxDetectionConfidence(detections)
+
+	// Open a new incident
+	if t.current == nil {
+		t.current = &trackedIncident{
+			summary: models.IncidentSummary{
+				ID:            uuid.New().String(),
+				OpenedAt:      now,
+				LastSeenAt:    now,
+				PeakFaceCount: len(detections),
+				MaxConfidence: maxConfidence,
+				Severity:      severity,
+			},
+			lastUpdateAt: now,
+			bestScore:    score,
+			trackIDs:     make(map[int64]struct{}),
+		}
+		t.current.countTracks(detections)
+
+		frameCopy := frame.Clone()
+		return &IncidentTransition{
+			Event:          models.IncidentEventOpened,
+			Incident:       t.current.summary,
+			Detections:     detections,
+			Frame:          &frameCopy,
+			AnnotatedFrame: cloneFrame(annotated),
+			EventID:        eventID,
+		}
+	}
+
+	incident := t.current
+	incident.summary.LastSeenAt = now
+
+	if len(detections) > incident.summary.PeakFaceCount {
+		incident.summary.PeakFaceCount = len(detections)
+		incident.pending = true
+	}
+	if maxConfidence > incident.summary.MaxConfidence {
+		incident.summary.MaxConfidence = maxConfidence
+		incident.pending = true
+	}
+	if alertSeverityRank[severity] > alertSeverityRank[incident.summary.Severity] {
+		incident.summary.Severity = severity
+		incident.pending = true
+	}
+
+	// A face not seen before in this incident is worth reporting
+	if incident.countTracks(detections) {
+		incident.pending = true
+	}
+
+	// Keep the best frame seen since the last report
+	if score > incident.bestScore {
+		incident.bestScore = score
+		incident.replaceBestFrame(frame.Clone(), cloneFrame(annotated), detections, eventID)
+		incident.pending = true
+	}
+
+	if !incident.pending || now.Sub(incident.lastUpdateAt) < updateInterval {
+		return nil
+	}
+
+	incident.lastUpdateAt = now
+	incident.pending = false
+	return incident.takeTransition(models.IncidentEventUpdated)
+}
+
+// Close ends the open incident, e.g. when the stream stops, and returns its close transition
+func (t *IncidentTracker) Close(now time.Time) *IncidentTransition {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.current == nil {
+		return nil
+	}
+	return t.closeLocked(now)
+}
+
+func (t *IncidentTracker) closeLocked(closedAt time.Time) *IncidentTransition {
+	incident := t.current
+	t.current = nil
+
+	// A best frame not yet reported travels with the close event
+	incident.summary.ClosedAt = &closedAt
+	return incident.takeTransition(models.IncidentEventClosed)
+}
+
+// CloseFrames releases the frames of the transition
+func (t *IncidentTransition) CloseFrames() {
+	if t.Frame != nil {
+		t.Frame.Close()
+	}
+	if t.AnnotatedFrame != nil {
+		t.AnnotatedFrame.Close()
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// takeTransition builds a transition and hands the best frames over to it
+func (i *trackedIncident) takeTransition(event string) *IncidentTransition {
+	transition := &IncidentTransition{
+		Event:          event,
+		Incident:       i.summary,
+		Detections:     i.bestDetections,
+		Frame:          i.bestFrame,
+		AnnotatedFrame: i.bestAnnotated,
+		EventID:        i.bestEventID,
+	}
+
+	i.bestFrame = nil
+	i.bestAnnotated = nil
+	i.bestDetections = nil
+	i.bestEventID = ""
+	return transition
+}
+
+// countTracks adds the tracks of the detections to the unique face count and reports whether any was new.
+// The count never drops below the peak face count, which covers untracked detections.
+func (i *trackedIncident) countTracks(detections []models.FaceDetection) bool {
+	added := false
+	for _, detection := range detections {
+		if detection.TrackID == 0 {
+			continue
+		}
+		if _, seen := i.trackIDs[detection.TrackID]; !seen {
+			i.trackIDs[detection.TrackID] = struct{}{}
+			added = true
+		}
+	}
+
+	i.summary.UniqueFaces = max(len(i.trackIDs), i.summary.PeakFaceCount)
+	return added
+}
+
+func (i *trackedIncident) replaceBestFrame(frame gocv.Mat, annotated *gocv.Mat, detections []models.FaceDetection, eventID string) {
+	if i.bestFrame != nil {
+		i.bestFrame.Close()
+	}
+	if i.bestAnnotated != nil {
+		i.bestAnnotated.Close()
+	}
+	i.bestFrame = &frame
+	i.bestAnnotated = annotated
+	i.bestDetections = detections
+	i.bestEventID = eventID
+}
+
+// cloneFrame copies an optional frame
+func cloneFrame(frame *gocv.Mat) *gocv.Mat {
+	if frame == nil {
+		return nil
+	}
+	frameCopy := frame.Clone()
+	return &frameCopy
+}
+
+// incidentScore ranks frames for the best snapshot: more faces first, then higher average confidence
+func incidentScore(detections []models.FaceDetection) float32 {
+	score := float32(len(detections))
+	for _, detection := range detections {
+		score += detection.Confidence / float32(len(detections))
+	}
+	return score
+}
+
+func maxDetectionConfidence(detections []models.FaceDetection) float32 {
+	maxConfidence := float32(0)
+	for _, detection := range detections {
+		if detection.Confidence > maxConfidence {
+			maxConfidence = detection.Confidence
+		}
+	}
+	return maxConfidence
+}
@@ -0,0 +1,163 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"sync"
+	"time"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	// How long frames keep being copied after the last read; MJPEG clients read at least once a second
+	latestFrameDemandWindow = 5 * time.Second
+
+	// How long a read waits for a fresh frame once copies have stopped for lack of readers
+	latestFrameWaitTimeout = 2 * time.Second
+)
+
+// ----------------------------------------------------------------------
+
+// LatestFrameBuffer keeps a copy of the most recent raw and annotated BGR24 frames of a session.
+// Frames are only copied while someone reads them: a read after an idle period waits for the next frame
+// and its overlay. The annotated frame is kept until a newer one replaces it, so frames the overlay
+// skips do not make annotated readers fall back to the raw frame.
+type LatestFrameBuffer struct {
+	mutex       sync.Mutex
+	width       int
+	height      int
+	raw         []byte
+	annotated   []byte
+	capturedAt  time.Time
+	annotatedAt time.Time
+	demandUntil time.Time
+	stale       bool
+	waking      bool
+	stored      chan struct{}
+}
+
+// NewLatestFrameBuffer creates a new latest-frame buffer for the given frame size
+func NewLatestFrameBuffer(width, height int) *LatestFrameBuffer {
+	return &LatestFrameBuffer{
+		width:  width,
+		height: height,
+		stored: make(chan struct{}),
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// StoreRaw copies the frame before any overlay has been drawn on it, when the frame has readers
+func (b *LatestFrameBuffer) StoreRaw(frame []byte, capturedAt time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// A previous frame whose overlay never came, e.g. when drawing failed, no longer holds readers back
+	b.wakeLocked()
+
+	if !b.wanted() {
+		b.stale = true
+		return
+	}
+
+	// The annotated frame kept from before an idle period is outdated; waiting reads are woken
+	// once the overlay of this frame has been stored or cleared
+	if b.stale || len(b.raw) == 0 {
+		b.annotated = b.annotated[:0]
+		b.waking = true
+	}
+
+	b.raw = copyInto(b.raw, frame)
+	b.capturedAt = capturedAt
+	b.stale = false
+}
+
+// StoreAnnotated copies the frame after the overlay has been drawn on it, when the frame has readers
+func (b *LatestFrameBuffer) StoreAnnotated(frame []byte) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.wanted() && !b.waking {
+		return
+	}
+	b.annotated = copyInto(b.annotated, frame)
+	b.annotatedAt = b.capturedAt
+	b.wakeLocked()
+}
+
+// ClearAnnotated drops the annotated frame once the overlay is disabled, so annotated reads get the raw frame
+func (b *LatestFrameBuffer) ClearAnnotated() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.annotated = b.annotated[:0]
+	b.wakeLocked()
+}
+
+// Snapshot returns a copy of the latest frame and its capture time; annotated falls back to raw when no
+// overlay was drawn. When frames were not being copied, it waits up to latestFrameWaitTimeout for a fresh one.
+func (b *LatestFrameBuffer) Snapshot(annotated bool) (frame []byte, width, height int, capturedAt time.Time, ok bool) {
+	b.mutex.Lock()
+	b.demandUntil = time.Now().Add(latestFrameDemandWindow)
+	if b.stale || b.waking || len(b.raw) == 0 {
+		stored := b.stored
+		b.mutex.Unlock()
+
+		select {
+		case <-stored:
+		case <-time.After(latestFrameWaitTimeout):
+		}
+		b.mutex.Lock()
+	}
+	defer b.mutex.Unlock()
+
+	if len(b.raw) == 0 {
+		return nil, 0, 0, time.Time{}, false
+	}
+
+	if annotated && len(b.annotated) > 0 {
+		return copyInto(nil, b.annotated), b.width, b.height, b.annotatedAt, true
+	}
+	return copyInto(nil, b.raw), b.width, b.height, b.capturedAt, true
+}
+
+// Demand keeps frames being copied for a reader that polls CapturedAt before reading
+func (b *LatestFrameBuffer) Demand() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.demandUntil = time.Now().Add(latestFrameDemandWindow)
+}
+
+// CapturedAt returns the capture time of the latest stored frame, or the zero time if there is none
+func (b *LatestFrameBuffer) CapturedAt() time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.capturedAt
+}
+
+// wakeLocked wakes the reads waiting for a fresh frame once its overlay is settled; the caller holds the mutex
+func (b *LatestFrameBuffer) wakeLocked() {
+	if !b.waking {
+		return
+	}
+	b.waking = false
+	close(b.stored)
+	b.stored = make(chan struct{})
+}
+
+// wanted reports whether the frame was read recently enough to keep copying; the caller holds the mutex
+func (b *LatestFrameBuffer) wanted() bool {
+	return time.Now().Before(b.demandUntil)
+}
+
+// ----------------------------------------------------------------------
+
+// copyInto copies src into dst, reusing dst's backing array when it is large enough
+func copyInto(dst, src []byte) []byte {
+	if cap(dst) < len(src) {
+		dst = make([]byte, len(src))
+	}
+	dst = dst[:len(src)]
+	copy(dst, src)
+	return dst
+}
@@ -0,0 +1,128 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	mjpegPollInterval = 20 * time.Millisecond
+)
+
+// ----------------------------------------------------------------------
+
+// MJPEGStream paces encoded preview frames for a single MJPEG client
+type MJPEGStream struct {
+	sm             *StreamManager
+	session        *StreamSession
+	query          models.SnapshotQuery
+	frameInterval  time.Duration
+	lastCapturedAt time.Time
+	lastSentAt     time.Time
+	closed         int32
+}
+
+// ----------------------------------------------------------------------
+
+// OpenMJPEGStream registers a new MJPEG client for a running stream
+func (sm *StreamManager) OpenMJPEGStream(cameraID string, query *models.MJPEGQuery) (*MJPEGStream, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A limit of 0 leaves the number of clients unlimited
+	maxClients := int32(sm.config.MJPEGMaxClients)
+	if clients := atomic.AddInt32(&session.mjpegClients, 1); maxClients > 0 && clients > maxClients {
+		atomic.AddInt32(&session.mjpegClients, -1)
+		return nil, capacityExceededError("MJPEG client limit reached for camera %s (%d)", cameraID, maxClients)
+	}
+
+	fps := query.FPS
+	if fps <= 0 || fps > sm.config.MJPEGMaxFPS {
+		fps = sm.config.MJPEGMaxFPS
+	}
+
+	maxWidth := query.MaxWidth
+	if maxWidth <= 0 || maxWidth > sm.config.MJPEGMaxWidth {
+		maxWidth = sm.config.MJPEGMaxWidth
+	}
+
+	utils.GetLogger().Infof("MJPEG client connected for camera %s (%d fps, max width %d, clients: %d)",
+		cameraID, fps, maxWidth, atomic.LoadInt32(&session.mjpegClients))
+
+	return &MJPEGStream{
+		sm:      sm,
+		session: session,
+		query: models.SnapshotQuery{
+			Format:   models.SnapshotFormatJPEG,
+			Variant:  query.Variant,
+			MaxWidth: maxWidth,
+			Quality:  query.Quality,
+		},
+		frameInterval: time.Second / time.Duration(fps),
+	}, nil
+}
+
+// ----------------------------------------------------------------------
+
+// NextFrame blocks until a new frame is due and returns it as JPEG.
+// It fails once the context is cancelled or the stream has been stopped.
+func (s *MJPEGStream) NextFrame(ctx context.Context) ([]byte, error) {
+	// Respect the client's frame rate
+	if wait := time.Until(s.lastSentAt.Add(s.frameInterval)); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	for {
+		if !s.isSessionActive() {
+			return nil, notFoundError("stream stopped for camera %s", s.session.CameraID)
+		}
+
+		// Only encode frames the processor has not already handed out
+		s.session.latestFrame.Demand()
+		if capturedAt := s.session.latestFrame.CapturedAt(); capturedAt.After(s.lastCapturedAt) {
+			snapshot, err := encodeLatestFrame(s.session, &s.query)
+			if err != nil {
+				return nil, err
+			}
+
+			s.lastCapturedAt = snapshot.CapturedAt
+			s.lastSentAt = time.Now()
+			return snapshot.Data, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(mjpegPollInterval):
+		}
+	}
+}
+
+// Close unregisters the client; it is safe to call more than once
+func (s *MJPEGStream) Close() {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return
+	}
+
+	clients := atomic.AddInt32(&s.session.mjpegClients, -1)
+	utils.GetLogger().Infof("MJPEG client disconnected for camera %s (clients: %d)", s.session.CameraID, clients)
+}
+
+// isSessionActive reports whether the stream this client is attached to is still registered
+func (s *MJPEGStream) isSessionActive() bool {
+	session, err := s.sm.getSession(s.session.CameraID)
+	return err == nil && session == s.session
+}
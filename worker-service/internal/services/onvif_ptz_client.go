@@ -0,0 +1,413 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+// Constants for ONVIF SOAP communication
+const (
+	onvifRequestTimeout = 5 * time.Second
+
+	onvifPTZNamespace    = "http://www.onvif.org/ver20/ptz/wsdl"
+	onvifMediaNamespace  = "http://www.onvif.org/ver10/media/wsdl"
+	onvifDeviceNamespace = "http://www.onvif.org/ver10/device/wsdl"
+
+	// The ONVIF core specification fixes the path of the device service
+	onvifDeviceServicePath = "/onvif/device_service"
+
+	onvifEnvelopeTemplate = `<?xml version="1.0" encoding="UTF-8"?>` +
+		`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"` +
+		` xmlns:tptz="http://www.onvif.org/ver20/ptz/wsdl"` +
+		` xmlns:trt="http://www.onvif.org/ver10/media/wsdl"` +
+		` xmlns:tds="http://www.onvif.org/ver10/device/wsdl"` +
+		` xmlns:tt="http://www.onvif.org/ver10/schema">` +
+		`<s:Header>%s</s:Header><s:Body>%s</s:Body></s:Envelope>`
+
+	onvifSecurityTemplate = `<Security s:mustUnderstand="1"` +
+		` xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">` +
+		`<UsernameToken><Username>%s</Username>` +
+		`<Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">%s</Password>` +
+		`<Nonce EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary">%s</Nonce>` +
+		`<Created xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">%s</Created>` +
+		`</UsernameToken></Security>`
+)
+
+// ----------------------------------------------------------------------
+
+// ONVIFPTZClient issues PTZ commands to an ONVIF camera over SOAP
+type ONVIFPTZClient struct {
+	cameraID     string
+	serviceURL   string
+	username     string
+	password     string
+	profileToken string
+	httpClient   *http.Client
+	mutex        sync.Mutex
+}
+
+// NewONVIFPTZClient creates a new ONVIF PTZ client for a camera
+func NewONVIFPTZClient(cameraID string, cfg *models.ONVIFConfig) *ONVIFPTZClient {
+	return &ONVIFPTZClient{
+		cameraID:     cameraID,
+		serviceURL:   cfg.ServiceURL,
+		username:     cfg.Username,
+		password:     cfg.Password,
+		profileToken: cfg.ProfileToken,
+		httpClient: &http.Client{
+			Timeout: onvifRequestTimeout,
+		},
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// ContinuousMove starts moving the camera with the given velocity until stopped or the timeout elapses
+func (c *ONVIFPTZClient) ContinuousMove(pan, tilt, zoom float64, timeout time.Duration) error {
+	profileToken, err := c.resolveProfileToken()
+	if err != nil {
+		return err
+	}
+
+	timeoutElement := ""
+	if timeout > 0 {
+		timeoutElement = fmt.Sprintf("<tptz:Timeout>PT%.3fS</tptz:Timeout>", timeout.Seconds())
+	}
+
+	body := fmt.Sprintf(
+		`<tptz:ContinuousMove><tptz:ProfileToken>%s</tptz:ProfileToken>`+
+			`<tptz:Velocity><tt:PanTilt x="%g" y="%g"/><tt:Zoom x="%g"/></tptz:Velocity>%s</tptz:ContinuousMove>`,
+		escapeXML(profileToken), pan, tilt, zoom, timeoutElement,
+	)
+
+	return c.call(onvifPTZNamespace+"/ContinuousMove", body, nil)
+}
+
+// Stop halts any ongoing pan, tilt and zoom movement
+func (c *ONVIFPTZClient) Stop() error {
+	profileToken, err := c.resolveProfileToken()
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		`<tptz:Stop><tptz:ProfileToken>%s</tptz:ProfileToken>`+
+			`<tptz:PanTilt>true</tptz:PanTilt><tptz:Zoom>true</tptz:Zoom></tptz:Stop>`,
+		escapeXML(profileToken),
+	)
+
+	return c.call(onvifPTZNamespace+"/Stop", body, nil)
+}
+
+// AbsoluteMove moves the camera to an absolute position
+func (c *ONVIFPTZClient) AbsoluteMove(pan, tilt, zoom float64, speed *float64) error {
+	profileToken, err := c.resolveProfileToken()
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		`<tptz:AbsoluteMove><tptz:ProfileToken>%s</tptz:ProfileToken>`+
+			`<tptz:Position><tt:PanTilt x="%g" y="%g"/><tt:Zoom x="%g"/></tptz:Position>%s</tptz:AbsoluteMove>`,
+		escapeXML(profileToken), pan, tilt, zoom, speedElement(speed),
+	)
+
+	return c.call(onvifPTZNamespace+"/AbsoluteMove", body, nil)
+}
+
+// RelativeMove moves the camera relative to its current position
+func (c *ONVIFPTZClient) RelativeMove(pan, tilt, zoom float64, speed *float64) error {
+	profileToken, err := c.resolveProfileToken()
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		`<tptz:RelativeMove><tptz:ProfileToken>%s</tptz:ProfileToken>`+
+			`<tptz:Translation><tt:PanTilt x="%g" y="%g"/><tt:Zoom x="%g"/></tptz:Translation>%s</tptz:RelativeMove>`,
+		escapeXML(profileToken), pan, tilt, zoom, speedElement(speed),
+	)
+
+	return c.call(onvifPTZNamespace+"/RelativeMove", body, nil)
+}
+
+// GotoPreset moves the camera to a stored preset
+func (c *ONVIFPTZClient) GotoPreset(presetToken string, speed *float64) error {
+	profileToken, err := c.resolveProfileToken()
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf(
+		`<tptz:GotoPreset><tptz:ProfileToken>%s</tptz:ProfileToken>`+
+			`<tptz:PresetToken>%s</tptz:PresetToken>%s</tptz:GotoPreset>`,
+		escapeXML(profileToken), escapeXML(presetToken), speedElement(speed),
+	)
+
+	return c.call(onvifPTZNamespace+"/GotoPreset", body, nil)
+}
+
+// GetPresets returns the presets stored on the camera
+func (c *ONVIFPTZClient) GetPresets() ([]models.PTZPreset, error) {
+	profileToken, err := c.resolveProfileToken()
+	if err != nil {
+		return nil, err
+	}
+
+	body := fmt.Sprintf(
+		`<tptz:GetPresets><tptz:ProfileToken>%s</tptz:ProfileToken></tptz:GetPresets>`,
+		escapeXML(profileToken),
+	)
+
+	var response struct {
+		Presets []struct {
+			Token    string `xml:"token,attr"`
+			Name     string `xml:"Name"`
+			Position *struct {
+				PanTilt *struct {
+					X float64 `xml:"x,attr"`
+					Y float64 `xml:"y,attr"`
+				} `xml:"PanTilt"`
+				Zoom *struct {
+					X float64 `xml:"x,attr"`
+				} `xml:"Zoom"`
+			} `xml:"PTZPosition"`
+		} `xml:"Body>GetPresetsResponse>Preset"`
+	}
+
+	if err := c.call(onvifPTZNamespace+"/GetPresets", body, &response); err != nil {
+		return nil, err
+	}
+
+	presets := make([]models.PTZPreset, 0, len(response.Presets))
+	for _, p := range response.Presets {
+		preset := models.PTZPreset{Token: p.Token, Name: p.Name}
+		if p.Position != nil {
+			position := &models.PTZPosition{}
+			if p.Position.PanTilt != nil {
+				position.Pan = p.Position.PanTilt.X
+				position.Tilt = p.Position.PanTilt.Y
+			}
+			if p.Position.Zoom != nil {
+				position.Zoom = p.Position.Zoom.X
+			}
+			preset.Position = position
+		}
+		presets = append(presets, preset)
+	}
+
+	return presets, nil
+}
+
+// ----------------------------------------------------------------------
+
+// resolveProfileToken returns the configured media profile, querying the camera for the first one if unset.
+// The camera is queried without holding the lock; the resolved token is cached for later commands.
+func (c *ONVIFPTZClient) resolveProfileToken() (string, error) {
+	c.mutex.Lock()
+	profileToken := c.profileToken
+	c.mutex.Unlock()
+
+	if profileToken != "" {
+		return profileToken, nil
+	}
+
+	mediaURL, err := c.resolveMediaURL()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve ONVIF media service: %w", err)
+	}
+
+	var response struct {
+		Profiles []struct {
+			Token string `xml:"token,attr"`
+		} `xml:"Body>GetProfilesResponse>Profiles"`
+	}
+
+	if err := c.callService(mediaURL, onvifMediaNamespace+"/GetProfiles", "<trt:GetProfiles/>", &response); err != nil {
+		return "", fmt.Errorf("failed to resolve ONVIF media profile: %w", err)
+	}
+
+	if len(response.Profiles) == 0 || response.Profiles[0].Token == "" {
+		return "", fmt.Errorf("camera %s reported no ONVIF media profiles", c.cameraID)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	// A concurrent command may have resolved the profile meanwhile; keep the first one
+	if c.profileToken == "" {
+		c.profileToken = response.Profiles[0].Token
+		utils.GetLogger().Infof("[ONVIF] Using media profile %s for camera %s", c.profileToken, c.cameraID)
+	}
+	return c.profileToken, nil
+}
+
+// resolveMediaURL asks the device service of the camera for the address of its media service,
+// with GetCapabilities and, for devices that only implement the newer call, GetServices
+func (c *ONVIFPTZClient) resolveMediaURL() (string, error) {
+	deviceURL, err := c.deviceServiceURL()
+	if err != nil {
+		return "", err
+	}
+
+	var capabilities struct {
+		XAddr string `xml:"Body>GetCapabilitiesResponse>Capabilities>Media>XAddr"`
+	}
+	capabilitiesErr := c.callService(deviceURL, onvifDeviceNamespace+"/GetCapabilities",
+		"<tds:GetCapabilities><tds:Category>Media</tds:Category></tds:GetCapabilities>", &capabilities)
+	if capabilitiesErr == nil && strings.TrimSpace(capabilities.XAddr) != "" {
+		return strings.TrimSpace(capabilities.XAddr), nil
+	}
+
+	var services struct {
+		Services []struct {
+			Namespace string `xml:"Namespace"`
+			XAddr     string `xml:"XAddr"`
+		} `xml:"Body>GetServicesResponse>Service"`
+	}
+	if err := c.callService(deviceURL, onvifDeviceNamespace+"/GetServices",
+		"<tds:GetServices><tds:IncludeCapability>false</tds:IncludeCapability></tds:GetServices>", &services); err != nil {
+		if capabilitiesErr != nil {
+			return "", capabilitiesErr
+		}
+		return "", err
+	}
+
+	for _, service := range services.Services {
+		if strings.TrimSpace(service.Namespace) == onvifMediaNamespace && strings.TrimSpace(service.XAddr) != "" {
+			return strings.TrimSpace(service.XAddr), nil
+		}
+	}
+	return "", fmt.Errorf("camera %s reported no ONVIF media service", c.cameraID)
+}
+
+// deviceServiceURL returns the device service address on the host of the PTZ service
+func (c *ONVIFPTZClient) deviceServiceURL() (string, error) {
+	serviceURL, err := url.Parse(c.serviceURL)
+	if err != nil || serviceURL.Host == "" {
+		return "", fmt.Errorf("invalid ONVIF service URL %q", c.serviceURL)
+	}
+
+	deviceURL := url.URL{Scheme: serviceURL.Scheme, Host: serviceURL.Host, Path: onvifDeviceServicePath}
+	return deviceURL.String(), nil
+}
+
+// call posts a SOAP request to the PTZ service and decodes the response envelope into result when non-nil
+func (c *ONVIFPTZClient) call(action string, body string, result interface{}) error {
+	return c.callService(c.serviceURL, action, body, result)
+}
+
+// callService posts a SOAP request to a service of the camera and decodes the response envelope into result when non-nil
+func (c *ONVIFPTZClient) callService(serviceURL, action string, body string, result interface{}) error {
+	envelope := fmt.Sprintf(onvifEnvelopeTemplate, c.securityHeader(), body)
+
+	req, err := http.NewRequest(http.MethodPost, serviceURL, bytes.NewBufferString(envelope))
+	if err != nil {
+		return fmt.Errorf("failed to create ONVIF request: %w", err)
+	}
+	req.Header.Set("Content-Type", fmt.Sprintf(`application/soap+xml; charset=utf-8; action="%s"`, action))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("ONVIF request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read ONVIF response: %w", err)
+	}
+
+	if fault := parseSOAPFault(responseBody); fault != "" {
+		return fmt.Errorf("ONVIF fault (status %d): %s", resp.StatusCode, fault)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("ONVIF error: status %d", resp.StatusCode)
+	}
+
+	if result != nil {
+		if err := xml.Unmarshal(responseBody, result); err != nil {
+			return fmt.Errorf("failed to parse ONVIF response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// securityHeader builds a WS-Security UsernameToken header with a password digest
+func (c *ONVIFPTZClient) securityHeader() string {
+	if c.username == "" {
+		return ""
+	}
+
+	nonce := make([]byte, 16)
+	_, _ = rand.Read(nonce)
+	created := time.Now().UTC().Format(time.RFC3339)
+
+	hash := sha1.New()
+	hash.Write(nonce)
+	hash.Write([]byte(created))
+	hash.Write([]byte(c.password))
+	digest := base64.StdEncoding.EncodeToString(hash.Sum(nil))
+
+	return fmt.Sprintf(onvifSecurityTemplate,
+		escapeXML(c.username), digest, base64.StdEncoding.EncodeToString(nonce), created)
+}
+
+// ----------------------------------------------------------------------
+
+// parseSOAPFault extracts a readable reason from a SOAP fault, or returns "" if there is none
+func parseSOAPFault(body []byte) string {
+	var envelope struct {
+		Fault *struct {
+			Code    string `xml:"Code>Value"`
+			Subcode string `xml:"Code>Subcode>Value"`
+			Reason  string `xml:"Reason>Text"`
+		} `xml:"Body>Fault"`
+	}
+
+	if err := xml.Unmarshal(body, &envelope); err != nil || envelope.Fault == nil {
+		return ""
+	}
+
+	parts := []string{}
+	for _, part := range []string{envelope.Fault.Code, envelope.Fault.Subcode, envelope.Fault.Reason} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "unknown fault"
+	}
+	return strings.Join(parts, " - ")
+}
+
+func speedElement(speed *float64) string {
+	if speed == nil {
+		return ""
+	}
+	return fmt.Sprintf(`<tptz:Speed><tt:PanTilt x="%g" y="%g"/><tt:Zoom x="%g"/></tptz:Speed>`, *speed, *speed, *speed)
+}
+
+func escapeXML(value string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(value))
+	return buf.String()
+}
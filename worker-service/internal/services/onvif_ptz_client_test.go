@@ -0,0 +1,235 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	testONVIFUsername = "admin"
+	testONVIFPassword = "secret"
+)
+
+// fakeONVIFCamera serves the device, media and PTZ services of a camera on separate paths,
+// checking the WS-Security password digest of every request
+type fakeONVIFCamera struct {
+	server *httptest.Server
+
+	// The device service only implements GetServices when set
+	servicesOnly bool
+
+	mutex   sync.Mutex
+	actions []string
+}
+
+func newFakeONVIFCamera(t *testing.T) *fakeONVIFCamera {
+	t.Helper()
+
+	camera := &fakeONVIFCamera{}
+	camera.server = httptest.NewServer(http.HandlerFunc(camera.handle))
+	t.Cleanup(camera.server.Close)
+	return camera
+}
+
+func (f *fakeONVIFCamera) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	_, action, _ := strings.Cut(r.Header.Get("Content-Type"), `action="`)
+	action = strings.TrimSuffix(action, `"`)
+	action = action[strings.LastIndex(action, "/")+1:]
+
+	f.mutex.Lock()
+	f.actions = append(f.actions, r.URL.Path+" "+action)
+	f.mutex.Unlock()
+
+	if !validPasswordDigest(body, testONVIFUsername, testONVIFPassword) {
+		writeSOAPFault(w, "ter:NotAuthorized")
+		return
+	}
+
+	switch r.URL.Path + " " + action {
+	case onvifDeviceServicePath + " GetCapabilities":
+		if f.servicesOnly {
+			writeSOAPFault(w, "ter:ActionNotSupported")
+			return
+		}
+		writeSOAPBody(w, fmt.Sprintf(`<tds:GetCapabilitiesResponse><tds:Capabilities>`+
+			`<tt:Media><tt:XAddr>%s/onvif/media</tt:XAddr></tt:Media>`+
+			`</tds:Capabilities></tds:GetCapabilitiesResponse>`, f.server.URL))
+	case onvifDeviceServicePath + " GetServices":
+		writeSOAPBody(w, fmt.Sprintf(`<tds:GetServicesResponse>`+
+			`<tds:Service><tds:Namespace>%s</tds:Namespace><tds:XAddr>%s/onvif/ptz</tds:XAddr></tds:Service>`+
+			`<tds:Service><tds:Namespace>%s</tds:Namespace><tds:XAddr>%s/onvif/media</tds:XAddr></tds:Service>`+
+			`</tds:GetServicesResponse>`, onvifPTZNamespace, f.server.URL, onvifMediaNamespace, f.server.URL))
+	case "/onvif/media GetProfiles":
+		writeSOAPBody(w, `<trt:GetProfilesResponse>`+
+			`<trt:Profiles token="profile_main"/><trt:Profiles token="profile_sub"/>`+
+			`</trt:GetProfilesResponse>`)
+	case "/onvif/ptz ContinuousMove", "/onvif/ptz Stop":
+		if !strings.Contains(string(body), "<tptz:ProfileToken>profile_main</tptz:ProfileToken>") {
+			writeSOAPFault(w, "ter:NoProfile")
+			return
+		}
+		writeSOAPBody(w, "<tptz:"+action+"Response/>")
+	default:
+		writeSOAPFault(w, "ter:ActionNotSupported")
+	}
+}
+
+// requests returns the path and action of every request received so far
+func (f *fakeONVIFCamera) requests() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.actions...)
+}
+
+// validPasswordDigest checks a UsernameToken: Base64(SHA1(nonce + created + password))
+func validPasswordDigest(body []byte, username, password string) bool {
+	var envelope struct {
+		Token struct {
+			Username string `xml:"Username"`
+			Password string `xml:"Password"`
+			Nonce    string `xml:"Nonce"`
+			Created  string `xml:"Created"`
+		} `xml:"Header>Security>UsernameToken"`
+	}
+	if err := xml.Unmarshal(body, &envelope); err != nil || envelope.Token.Username != username {
+		return false
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(envelope.Token.Nonce)
+	if err != nil {
+		return false
+	}
+
+	hash := sha1.New()
+	hash.Write(nonce)
+	hash.Write([]byte(envelope.Token.Created))
+	hash.Write([]byte(password))
+	return envelope.Token.Password == base64.StdEncoding.EncodeToString(hash.Sum(nil))
+}
+
+func writeSOAPBody(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	fmt.Fprintf(w, onvifEnvelopeTemplate, "", body)
+}
+
+func writeSOAPFault(w http.ResponseWriter, subcode string) {
+	w.Header().Set("Content-Type", "application/soap+xml; charset=utf-8")
+	w.WriteHeader(http.StatusBadRequest)
+	fmt.Fprintf(w, onvifEnvelopeTemplate, "", `<s:Fault><s:Code><s:Value>s:Sender</s:Value>`+
+		`<s:Subcode><s:Value>`+subcode+`</s:Value></s:Subcode></s:Code>`+
+		`<s:Reason><s:Text xml:lang="en">rejected</s:Text></s:Reason></s:Fault>`)
+}
+
+func newTestPTZClient(camera *fakeONVIFCamera, password string) *ONVIFPTZClient {
+	return NewONVIFPTZClient("cam-1", &models.ONVIFConfig{
+		ServiceURL: camera.server.URL + "/onvif/ptz",
+		Username:   testONVIFUsername,
+		Password:   password,
+	})
+}
+
+// ----------------------------------------------------------------------
+
+func TestONVIFPTZClientResolvesProfileFromMediaService(t *testing.T) {
+	camera := newFakeONVIFCamera(t)
+	client := newTestPTZClient(camera, testONVIFPassword)
+
+	if err := client.ContinuousMove(0.5, 0, 0, 0); err != nil {
+		t.Fatalf("ContinuousMove: %v", err)
+	}
+	if err := client.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	want := []string{
+		onvifDeviceServicePath + " GetCapabilities",
+		"/onvif/media GetProfiles",
+		"/onvif/ptz ContinuousMove",
+		"/onvif/ptz Stop",
+	}
+	if got := camera.requests(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("requests:\n%s\nwant (profile resolved once, through the media service):\n%s",
+			strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestONVIFPTZClientFallsBackToGetServices(t *testing.T) {
+	camera := newFakeONVIFCamera(t)
+	camera.servicesOnly = true
+	client := newTestPTZClient(camera, testONVIFPassword)
+
+	profileToken, err := client.resolveProfileToken()
+	if err != nil {
+		t.Fatalf("resolveProfileToken: %v", err)
+	}
+	if profileToken != "profile_main" {
+		t.Errorf("profile token = %q, want the first profile", profileToken)
+	}
+}
+
+func TestONVIFPTZClientKeepsConfiguredProfile(t *testing.T) {
+	camera := newFakeONVIFCamera(t)
+	client := newTestPTZClient(camera, testONVIFPassword)
+	client.profileToken = "profile_main"
+
+	if err := client.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if got := camera.requests(); len(got) != 1 || got[0] != "/onvif/ptz Stop" {
+		t.Errorf("requests = %v, want only the Stop command", got)
+	}
+}
+
+func TestONVIFPTZClientRejectedDigest(t *testing.T) {
+	camera := newFakeONVIFCamera(t)
+	client := newTestPTZClient(camera, "wrong")
+
+	err := client.Stop()
+	if err == nil || !strings.Contains(err.Error(), "ter:NotAuthorized") {
+		t.Fatalf("Stop with a wrong password = %v, want a NotAuthorized fault", err)
+	}
+	if client.profileToken != "" {
+		t.Errorf("profile token cached after a failed resolution: %q", client.profileToken)
+	}
+}
+
+func TestONVIFPTZClientConcurrentResolution(t *testing.T) {
+	camera := newFakeONVIFCamera(t)
+	client := newTestPTZClient(camera, testONVIFPassword)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- client.Stop()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Stop: %v", err)
+		}
+	}
+	if client.profileToken != "profile_main" {
+		t.Errorf("profile token = %q", client.profileToken)
+	}
+}
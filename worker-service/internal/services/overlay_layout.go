@@ -0,0 +1,152 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"image/color"
+	"time"
+	"worker-service/internal/models"
+
+	"gocv.io/x/gocv"
+)
+
+// ----------------------------------------------------------------------
+
+// Layout constants, in pixels at the reference frame height
+const (
+	LayoutReferenceHeight = 720
+	LayoutMargin          = 20
+	LayoutLineGap         = 10
+	LayoutMinFontSize     = 10
+)
+
+// anchorOrder is the order in which anchored lines are collected, and so stacked
+var anchorOrder = []string{
+	models.AnchorTopLeft,
+	models.AnchorTopRight,
+	models.AnchorBottomLeft,
+	models.AnchorBottomRight,
+}
+
+// ----------------------------------------------------------------------
+
+// overlayLayout scales overlay metrics with the frame height so the overlay keeps its proportions
+// from low-resolution substreams to 4K
+type overlayLayout struct {
+	scale  float64
+	width  int
+	height int
+}
+
+// overlayLine is a line of overlay text waiting to be placed at its anchor
+type overlayLine struct {
+	text  string
+	size  int
+	style TextStyle
+}
+
+func newOverlayLayout(frame *gocv.Mat) overlayLayout {
+	return overlayLayout{
+		scale:  float64(frame.Rows()) / LayoutReferenceHeight,
+		width:  frame.Cols(),
+		height: frame.Rows(),
+	}
+}
+
+// px scales a length given at the reference height, to at least one pixel
+func (l overlayLayout) px(length float64) int {
+	return max(1, int(length*l.scale+0.5))
+}
+
+// fontSize scales a font size given at the reference height, never below the readable minimum
+func (l overlayLayout) fontSize(size int, relative float64) int {
+	return max(LayoutMinFontSize, l.px(float64(size)*relative))
+}
+
+// textStyle returns the configured text style in a color with shadow and padding scaled to the frame
+func (l overlayLayout) textStyle(config *models.OverlayConfig, textColor color.RGBA) TextStyle {
+	style := textStyle(config, textColor)
+	style.ShadowOffset = l.px(TextShadowOffset)
+	style.Padding = l.px(TextBackgroundPadding)
+	return style
+}
+
+// ----------------------------------------------------------------------
+
+// drawAnchored stacks the lines of each anchor from its corner, in the order they were added:
+// top anchors grow downwards from the top margin, bottom anchors end at the bottom margin
+func (or *OverlayRenderer) drawAnchored(frame *gocv.Mat, layout overlayLayout, lines map[string][]overlayLine) {
+	margin := layout.px(LayoutMargin)
+	gap := layout.px(LayoutLineGap)
+
+	for _, anchor := range anchorOrder {
+		anchored := lines[anchor]
+		if len(anchored) == 0 {
+			continue
+		}
+
+		bottom := anchor == models.AnchorBottomLeft || anchor == models.AnchorBottomRight
+		right := anchor == models.AnchorTopRight || anchor == models.AnchorBottomRight
+
+		y := margin
+		if bottom {
+			total := 0
+			for i, line := range anchored {
+				ascent, descent := or.text.Metrics(line.size)
+				total += ascent + descent
+				if i > 0 {
+					total += gap
+				}
+			}
+			y = layout.height - margin - total
+		}
+
+		for _, line := range anchored {
+			ascent, descent := or.text.Metrics(line.size)
+
+			x := margin
+			if right {
+				x = layout.width - margin - or.text.Measure(line.text, line.size)
+			}
+
+			or.drawText(frame, line.text, x, y+ascent, line.size, line.style)
+			y += ascent + descent + gap
+		}
+	}
+}
+
+// anchorOrDefault returns the configured anchor of an element, or its default when unset
+func anchorOrDefault(anchor, defaultAnchor string) string {
+	if anchor == "" {
+		return defaultAnchor
+	}
+	return anchor
+}
+
+// validateAnchor checks that an element anchor is one of the frame corners or unset
+func validateAnchor(element, anchor string) error {
+	switch anchor {
+	case "", models.AnchorTopLeft, models.AnchorTopRight, models.AnchorBottomLeft, models.AnchorBottomRight:
+		return nil
+	default:
+		return invalidParameterError("invalid %s anchor %q", element, anchor)
+	}
+}
+
+// loadTimeZone resolves the overlay time zone; an empty name is the local time zone
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, invalidParameterError("invalid time zone %q", name)
+	}
+	return location, nil
+}
+
+// clampBaseline keeps a text baseline low enough for text of the given ascent to stay below the top margin
+func (l overlayLayout) clampBaseline(y, ascent int) int {
+	return max(y, l.px(LayoutMargin)+ascent)
+}
@@ -18,22 +18,15 @@ import (
 
 // Constants for overlay rendering
 const (
-	// Text rendering constants
-	DefaultTextScale    = 2.0
-	LargeTextScale      = 2.5
-	TextShadowThickness = 4
-	TextMainThickness   = 3
-	TextShadowOffset    = 2
-
-	// Position constants -
-	TextTopMargin        = 40
-	TextBottomMargin     = 30
-	TextSideMargin       = 20
-	TextMinY             = 35
-	ConfidenceTextOffset = -8
-
-	// Section spacing
-	SectionSpacing = 35
+	// Text rendering constants (scales are relative to the configured font size,
+	// lengths are in pixels at the layout reference height)
+	LargeTextScale        = 1.25
+	TextShadowOffset      = 2
+	TextBackgroundPadding = 6
+
+	// Gap between a box and its confidence text above it, and its track ID below it
+	ConfidenceTextOffset = 8
+	TrackIDTextOffset    = 6
 
 	// Color constants
 	ShadowColorR = 0
@@ -41,11 +34,6 @@ const (
 	ShadowColorB = 0
 	ShadowColorA = 255
 
-	// High-contrast text colors
-	PrimaryTextColorR = 255 // Bright White
-	PrimaryTextColorG = 255
-	PrimaryTextColorB = 255
-
 	// Accent colors for important info
 	AccentTextColorR = 255 // Bright Yellow
 	AccentTextColorG = 255
@@ -55,6 +43,25 @@ const (
 	WarningTextColorG = 50
 	WarningTextColorB = 50
 
+	// Detection zone colors and labels
+	ZoneIncludeColorR = 0 // Green
+	ZoneIncludeColorG = 200
+	ZoneIncludeColorB = 0
+
+	ZoneExcludeColorR = 255 // Red
+	ZoneExcludeColorG = 50
+	ZoneExcludeColorB = 50
+
+	ZoneLineThickness = 2
+	ZoneLabelScale    = 0.75
+	ZoneLabelOffset   = 6
+
+	// Bounding box line width and font size limits
+	MinOverlayLineWidth = 1
+	MaxOverlayLineWidth = 20
+	MinOverlayFontSize  = 8
+	MaxOverlayFontSize  = 128
+
 	// Alpha channel for colors
 	FullAlpha = 255
 )
@@ -65,6 +72,10 @@ const (
 type OverlayRenderer struct {
 	cameraID       string
 	config         *models.OverlayConfig
+	zones          []models.DetectionZone
+	text           *overlayText
+	images         *overlayImages
+	timeZone       *time.Location
 	mutex          sync.RWMutex
 	totalFrames    int64
 	renderedFrames int64
@@ -72,11 +83,14 @@ type OverlayRenderer struct {
 	lastError      error
 }
 
-// NewOverlayRenderer creates a new overlay renderer
-func NewOverlayRenderer(cameraID string) *OverlayRenderer {
+// NewOverlayRenderer creates a new overlay renderer drawing text with the given fonts and logos from logoDir
+func NewOverlayRenderer(cameraID string, fonts *OverlayFonts, logoDir string) *OverlayRenderer {
 	return &OverlayRenderer{
 		cameraID:       cameraID,
 		config:         models.DefaultOverlayConfig(),
+		text:           newOverlayText(fonts),
+		images:         newOverlayImages(logoDir),
+		timeZone:       time.Local,
 		totalFrames:    0,
 		renderedFrames: 0,
 		renderErrors:   0,
@@ -85,7 +99,7 @@ func NewOverlayRenderer(cameraID string) *OverlayRenderer {
 
 // ----------------------------------------------------------------------
 
-// RenderDetections renders face detections on a frame
+// RenderDetections renders face detections on a frame; fps is the measured processing rate shown with ShowFPS
 func (or *OverlayRenderer) RenderDetections(
 	frame *gocv.Mat,
 	detections []models.FaceDetection,
@@ -118,11 +132,11 @@ func (or *OverlayRenderer) RenderDetections(
 		A: FullAlpha,
 	}
 
-	// Use high-contrast white for all text
+	// Primary text color, high-contrast white by default
 	textColor := color.RGBA{
-		R: PrimaryTextColorR, // Bright White
-		G: PrimaryTextColorG,
-		B: PrimaryTextColorB,
+		R: config.TextColor.R,
+		G: config.TextColor.G,
+		B: config.TextColor.B,
 		A: FullAlpha,
 	}
 
@@ -142,25 +156,46 @@ func (or *OverlayRenderer) RenderDetections(
 		A: FullAlpha,
 	}
 
+	layout := newOverlayLayout(frame)
+	fontSize := layout.fontSize(config.FontSize, 1)
+
+	// Draw the watermark and logo underneath everything else
+	or.drawImageOverlays(frame, layout)
+
+	// Draw detection zones underneath the boxes
+	if config.DrawZones {
+		for _, zone := range or.zones {
+			or.drawZone(frame, zone, config, layout)
+		}
+	}
+
 	// Draw bounding boxes for detected faces
 	if config.DrawBoundingBox && len(detections) > 0 {
+		lineWidth := layout.px(float64(config.LineWidth))
+		confidenceAscent, _ := or.text.Metrics(fontSize)
+		trackIDSize := layout.fontSize(config.FontSize, ZoneLabelScale)
+		trackIDAscent, trackIDDescent := or.text.Metrics(trackIDSize)
+
 		for i, detection := range detections {
 			pt1 := image.Pt(int(detection.X), int(detection.Y))
 			pt2 := image.Pt(int(detection.X+detection.Width), int(detection.Y+detection.Height))
 
 			// Draw rectangle (bounding box)
-			gocv.Rectangle(frame, image.Rectangle{Min: pt1, Max: pt2}, boxColor, 4)
+			gocv.Rectangle(frame, image.Rectangle{Min: pt1, Max: pt2}, boxColor, lineWidth)
 
 			// Optionally draw confidence score on box
 			if config.ShowConfidence && detection.Confidence > 0 {
 				confidenceText := fmt.Sprintf("%.0f%%", detection.Confidence*100)
-				textY := int(detection.Y) + ConfidenceTextOffset
-				if textY < TextMinY {
-					textY = TextMinY
-				}
+				textY := layout.clampBaseline(int(detection.Y)-layout.px(ConfidenceTextOffset), confidenceAscent)
 				// Use accent color for confidence text
-				gocv.PutText(frame, confidenceText, image.Pt(int(detection.X), textY),
-					gocv.FontHersheyPlain, DefaultTextScale, accentColor, TextMainThickness)
+				or.drawText(frame, confidenceText, int(detection.X), textY, fontSize, layout.textStyle(config, accentColor))
+			}
+
+			// Optionally label the box with its track ID, below the box and inside the frame
+			if config.ShowTrackIDs && detection.TrackID > 0 {
+				trackText := fmt.Sprintf("#%d", detection.TrackID)
+				textY := min(pt2.Y+layout.px(TrackIDTextOffset)+trackIDAscent, frame.Rows()-trackIDDescent-layout.px(TrackIDTextOffset))
+				or.drawText(frame, trackText, int(detection.X), textY, trackIDSize, layout.textStyle(config, boxColor))
 			}
 
 			logger.Debugf("[OverlayRenderer] Face %d: box at (%d,%d) size %dx%d",
@@ -168,42 +203,29 @@ func (or *OverlayRenderer) RenderDetections(
 		}
 	}
 
-	// Draw overlay information (Location, FPS, Detection count)
+	// Draw overlay information, each element stacked from the corner it is anchored to
 	if config.DrawText {
-		// Helper function to draw text with shadow for better visibility
-		drawVisibleText := func(text string, x, y int, scale float64, textColor color.RGBA) {
-
-			shadowColor := color.RGBA{
-				R: ShadowColorR,
-				G: ShadowColorG,
-				B: ShadowColorB,
-				A: ShadowColorA,
-			}
-			gocv.PutText(frame, text, image.Pt(x+TextShadowOffset, y+TextShadowOffset),
-				gocv.FontHersheyPlain, scale, shadowColor, TextShadowThickness)
-
-			// Draw main text
-			gocv.PutText(frame, text, image.Pt(x, y),
-				gocv.FontHersheyPlain, scale, textColor, TextMainThickness)
+		lines := make(map[string][]overlayLine)
+		addLine := func(anchor, defaultAnchor, text string, size int, lineColor color.RGBA) {
+			anchor = anchorOrDefault(anchor, defaultAnchor)
+			lines[anchor] = append(lines[anchor], overlayLine{text: text, size: size, style: layout.textStyle(config, lineColor)})
 		}
 
-		currentY := TextTopMargin
+		if config.ShowCameraName && cameraName != "" {
+			addLine(config.CameraNameAnchor, models.AnchorTopLeft, cameraName, fontSize, textColor)
+		}
 
-		// Location (top-left, below camera name)
-		if location != "" {
+		if config.ShowLocation && location != "" {
 			locationText := fmt.Sprintf("Location: %s", location)
-			drawVisibleText(locationText, TextSideMargin, currentY, DefaultTextScale, textColor)
-			currentY += SectionSpacing
+			addLine(config.LocationAnchor, models.AnchorTopLeft, locationText, fontSize, textColor)
 		}
 
-		// FPS (top-right) - MOVED to top for better visibility
 		if config.ShowFPS {
 			fpsText := fmt.Sprintf("FPS: %.1f", fps)
-			textSize := gocv.GetTextSize(fpsText, gocv.FontHersheyPlain, DefaultTextScale, TextMainThickness)
-			drawVisibleText(fpsText, frame.Cols()-textSize.X-TextSideMargin, TextTopMargin, DefaultTextScale, textColor)
+			addLine(config.FPSAnchor, models.AnchorTopRight, fpsText, fontSize, textColor)
 		}
 
-		// Faces Detected (bottom-left) - WITH COLOR CODING
+		// Faces detected, color coded by count
 		if config.ShowDetectionCount {
 			faceCount := len(detections)
 			var detectionText string
@@ -220,14 +242,23 @@ func (or *OverlayRenderer) RenderDetections(
 				countColor = warningColor // Red color for multiple faces
 			}
 
-			drawVisibleText(detectionText, TextSideMargin, frame.Rows()-TextBottomMargin, LargeTextScale, countColor)
+			addLine(config.FaceCountAnchor, models.AnchorBottomLeft, detectionText, layout.fontSize(config.FontSize, LargeTextScale), countColor)
+		}
+
+		if config.ShowTimestamp {
+			format := config.TimestampFormat
+			if format == "" {
+				format = models.DefaultTimestampFormat
+			}
+			timestampText := fmt.Sprintf("Time: %s", time.Now().In(or.timeZone).Format(format))
+			addLine(config.TimestampAnchor, models.AnchorBottomRight, timestampText, fontSize, textColor)
+		}
+
+		if config.CustomText != "" {
+			addLine(config.CustomTextAnchor, models.AnchorTopRight, config.CustomText, fontSize, textColor)
 		}
 
-		// ADDED: Timestamp (bottom-right)
-		timestamp := time.Now().Format("15:04:05")
-		timestampText := fmt.Sprintf("Time: %s", timestamp)
-		timestampSize := gocv.GetTextSize(timestampText, gocv.FontHersheyPlain, DefaultTextScale, TextMainThickness)
-		drawVisibleText(timestampText, frame.Cols()-timestampSize.X-TextSideMargin, frame.Rows()-TextBottomMargin, DefaultTextScale, textColor)
+		or.drawAnchored(frame, layout, lines)
 	}
 
 	renderTime := time.Since(startTime)
@@ -238,21 +269,170 @@ func (or *OverlayRenderer) RenderDetections(
 	return nil
 }
 
-// UpdateConfig updates the overlay configuration
-func (or *OverlayRenderer) UpdateConfig(config *models.OverlayConfig) {
+// RenderImageOverlays composites only the watermark and logo, for frames that carry no annotations
+func (or *OverlayRenderer) RenderImageOverlays(frame *gocv.Mat) {
+	or.mutex.Lock()
+	defer or.mutex.Unlock()
+
+	if frame == nil || frame.Empty() {
+		return
+	}
+	or.drawImageOverlays(frame, newOverlayLayout(frame))
+}
+
+// drawImageOverlays blends the watermark and the logo into the frame
+func (or *OverlayRenderer) drawImageOverlays(frame *gocv.Mat, layout overlayLayout) {
+	config := or.config
+	if config.WatermarkText == "" && or.images.logo == nil {
+		return
+	}
+
+	pixels, err := newFramePixels(frame)
+	if err != nil {
+		utils.GetLogger().Debugf("[OverlayRenderer] Failed to draw image overlays: %v", err)
+		return
+	}
+
+	textColor := color.RGBA{R: config.TextColor.R, G: config.TextColor.G, B: config.TextColor.B, A: FullAlpha}
+	or.images.drawWatermark(pixels, or.text, config, layout, layout.textStyle(config, textColor))
+	or.images.drawLogo(pixels, config, layout)
+}
+
+// drawZone outlines a detection zone and labels it with its name
+func (or *OverlayRenderer) drawZone(frame *gocv.Mat, zone models.DetectionZone, config *models.OverlayConfig, layout overlayLayout) {
+	zoneColor := color.RGBA{R: ZoneIncludeColorR, G: ZoneIncludeColorG, B: ZoneIncludeColorB, A: FullAlpha}
+	if zone.Type == models.ZoneTypeExclude {
+		zoneColor = color.RGBA{R: ZoneExcludeColorR, G: ZoneExcludeColorG, B: ZoneExcludeColorB, A: FullAlpha}
+	}
+
+	points := polygonPixels(zone.Points, frame.Cols(), frame.Rows())
+
+	polygon := gocv.NewPointsVectorFromPoints([][]image.Point{points})
+	defer polygon.Close()
+
+	gocv.Polylines(frame, polygon, true, zoneColor, layout.px(ZoneLineThickness))
+
+	labelSize := layout.fontSize(config.FontSize, ZoneLabelScale)
+	labelAscent, _ := or.text.Metrics(labelSize)
+	labelY := layout.clampBaseline(points[0].Y-layout.px(ZoneLabelOffset), labelAscent)
+	or.drawText(frame, zone.Name, points[0].X, labelY, labelSize, layout.textStyle(config, zoneColor))
+}
+
+// drawText draws a line of text with its baseline at (x, y); failures are logged rather than failing the frame
+func (or *OverlayRenderer) drawText(frame *gocv.Mat, text string, x, y int, size int, style TextStyle) {
+	if err := or.text.Draw(frame, text, image.Pt(x, y), size, style); err != nil {
+		utils.GetLogger().Debugf("[OverlayRenderer] Failed to draw text %q: %v", text, err)
+	}
+}
+
+// textStyle returns the configured text style in a color; the background box replaces the shadow when enabled
+func textStyle(config *models.OverlayConfig, textColor color.RGBA) TextStyle {
+	return TextStyle{
+		Color:      textColor,
+		Shadow:     true,
+		Background: config.TextBackground,
+		BackgroundColor: color.RGBA{
+			R: config.BackgroundColor.R,
+			G: config.BackgroundColor.G,
+			B: config.BackgroundColor.B,
+			A: FullAlpha,
+		},
+		BackgroundAlpha: config.BackgroundAlpha,
+	}
+}
+
+// SetZones sets the detection zones drawn when DrawZones is enabled
+func (or *OverlayRenderer) SetZones(zones []models.DetectionZone) {
+	or.mutex.Lock()
+	defer or.mutex.Unlock()
+	or.zones = zones
+}
+
+// SetDrawZones enables or disables drawing of detection zones
+func (or *OverlayRenderer) SetDrawZones(enabled bool) {
+	or.mutex.Lock()
+	defer or.mutex.Unlock()
+	or.config.DrawZones = enabled
+}
+
+// UpdateConfig validates and replaces the overlay configuration; it applies from the next frame
+func (or *OverlayRenderer) UpdateConfig(config *models.OverlayConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.LineWidth < MinOverlayLineWidth || config.LineWidth > MaxOverlayLineWidth {
+		return invalidParameterError("line width must be between %d and %d", MinOverlayLineWidth, MaxOverlayLineWidth)
+	}
+	if config.FontSize < MinOverlayFontSize || config.FontSize > MaxOverlayFontSize {
+		return invalidParameterError("font size must be between %d and %d", MinOverlayFontSize, MaxOverlayFontSize)
+	}
+	if config.BackgroundAlpha < 0 || config.BackgroundAlpha > 1 {
+		return invalidParameterError("background alpha must be between 0 and 1")
+	}
+
+	if config.LogoScale < 0 || config.LogoScale > 1 || config.LogoOpacity < 0 || config.LogoOpacity > 1 {
+		return invalidParameterError("logo scale and opacity must be between 0 and 1")
+	}
+	if config.WatermarkOpacity < 0 || config.WatermarkOpacity > 1 {
+		return invalidParameterError("watermark opacity must be between 0 and 1")
+	}
+
+	anchors := map[string]string{
+		"camera name": config.CameraNameAnchor,
+		"location":    config.LocationAnchor,
+		"timestamp":   config.TimestampAnchor,
+		"FPS":         config.FPSAnchor,
+		"face count":  config.FaceCountAnchor,
+		"custom text": config.CustomTextAnchor,
+		"logo":        config.LogoAnchor,
+	}
+	for element, anchor := range anchors {
+		if err := validateAnchor(element, anchor); err != nil {
+			return err
+		}
+	}
+
+	timeZone, err := loadTimeZone(config.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	// Decode the logo before taking the lock; an unchanged logo is not reloaded
+	or.mutex.RLock()
+	logoChanged := config.LogoFile != or.config.LogoFile
+	or.mutex.RUnlock()
+
+	var logo image.Image
+	if logoChanged {
+		if logo, err = or.images.loadLogo(config.LogoFile); err != nil {
+			return err
+		}
+	}
+
 	or.mutex.Lock()
 	defer or.mutex.Unlock()
 
-	if config != nil {
-		or.config = config
+	// Faces are cached per size; drop them when the size changes
+	if config.FontSize != or.config.FontSize {
+		or.text.reset()
+	}
+
+	configCopy := *config
+	or.config = &configCopy
+	or.timeZone = timeZone
+	if logoChanged {
+		or.images.setLogo(logo)
 	}
+	return nil
 }
 
-// GetConfig returns the current overlay configuration
+// GetConfig returns a copy of the current overlay configuration
 func (or *OverlayRenderer) GetConfig() *models.OverlayConfig {
 	or.mutex.RLock()
 	defer or.mutex.RUnlock()
-	return or.config
+
+	config := *or.config
+	return &config
 }
 
 // GetStatistics returns rendering statistics
@@ -0,0 +1,357 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"worker-service/internal/utils"
+
+	"gocv.io/x/gocv"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// ----------------------------------------------------------------------
+
+// fontDPI makes font sizes pixel sizes
+const fontDPI = 72
+
+// ----------------------------------------------------------------------
+
+// OverlayFonts is the ordered list of fonts overlay text is drawn with: each character uses the first font
+// that has a glyph for it, so scripts missing from the primary font are taken from the fallbacks.
+// The embedded Go font is always the last resort. Parsed fonts are shared by all cameras.
+type OverlayFonts struct {
+	fonts []*opentype.Font
+	names []string
+}
+
+// LoadOverlayFonts parses the TrueType/OpenType fonts at paths, in order; empty paths and fonts that fail to load are skipped.
+// For collections (.ttc/.otc) the first font of the collection is used.
+func LoadOverlayFonts(paths []string) *OverlayFonts {
+	logger := utils.GetLogger()
+	fonts := &OverlayFonts{}
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		f, err := parseFontFile(path)
+		if err != nil {
+			logger.Warnf("Overlay font %s not loaded: %v", path, err)
+			continue
+		}
+		fonts.fonts = append(fonts.fonts, f)
+		fonts.names = append(fonts.names, filepath.Base(path))
+	}
+
+	goRegular, err := opentype.Parse(goregular.TTF)
+	if err != nil {
+		logger.Errorf("Failed to parse the embedded overlay font: %v", err)
+	} else {
+		fonts.fonts = append(fonts.fonts, goRegular)
+		fonts.names = append(fonts.names, "Go Regular")
+	}
+
+	logger.Infof("Overlay fonts: %s", strings.Join(fonts.names, ", "))
+	return fonts
+}
+
+func parseFontFile(path string) (*opentype.Font, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ttc", ".otc":
+		collection, err := opentype.ParseCollection(data)
+		if err != nil {
+			return nil, err
+		}
+		return collection.Font(0)
+	default:
+		return opentype.Parse(data)
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// TextStyle controls how a line of overlay text is drawn
+type TextStyle struct {
+	Color           color.RGBA
+	Shadow          bool
+	ShadowOffset    int
+	Background      bool
+	BackgroundColor color.RGBA
+	BackgroundAlpha float64
+	Padding         int
+}
+
+// overlayText draws Unicode text on frames with the overlay fonts. It caches one face per font and size
+// and is not safe for concurrent use; each overlay renderer owns one.
+type overlayText struct {
+	fonts *OverlayFonts
+	faces map[int][]font.Face
+	buf   sfnt.Buffer
+}
+
+// textRun is a part of a line drawn with a single font
+type textRun struct {
+	text string
+	face font.Face
+}
+
+func newOverlayText(fonts *OverlayFonts) *overlayText {
+	if fonts == nil {
+		fonts = LoadOverlayFonts(nil)
+	}
+	return &overlayText{fonts: fonts, faces: make(map[int][]font.Face)}
+}
+
+// reset drops the cached faces, e.g. after the font size changed
+func (t *overlayText) reset() {
+	t.faces = make(map[int][]font.Face)
+}
+
+// facesForSize returns one face per font at a pixel size, creating them on first use
+func (t *overlayText) facesForSize(size int) []font.Face {
+	if faces, ok := t.faces[size]; ok {
+		return faces
+	}
+
+	// Faces stay aligned with the fonts; a font without a face at this size is left nil
+	faces := make([]font.Face, len(t.fonts.fonts))
+	for i, f := range t.fonts.fonts {
+		face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: float64(size), DPI: fontDPI, Hinting: font.HintingFull})
+		if err == nil {
+			faces[i] = face
+		}
+	}
+	t.faces[size] = faces
+	return faces
+}
+
+// runs splits text into runs of characters drawn with the same font
+func (t *overlayText) runs(text string, size int) []textRun {
+	faces := t.facesForSize(size)
+
+	var runs []textRun
+	start, current := 0, -1
+	for i, r := range text {
+		index := t.fontFor(r, faces)
+		if index < 0 {
+			return nil
+		}
+		if index != current && i > start {
+			runs = append(runs, textRun{text: text[start:i], face: faces[current]})
+			start = i
+		}
+		current = index
+	}
+	if start < len(text) {
+		runs = append(runs, textRun{text: text[start:], face: faces[current]})
+	}
+	return runs
+}
+
+// fontFor returns the first font with a glyph for r, or the first usable font when none has one.
+// It returns -1 when no font is usable.
+func (t *overlayText) fontFor(r rune, faces []font.Face) int {
+	first := -1
+	for i, face := range faces {
+		if face == nil {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		if glyph, err := t.fonts.fonts[i].GlyphIndex(&t.buf, r); err == nil && glyph != 0 {
+			return i
+		}
+	}
+	return first
+}
+
+// bounds returns the ink bounds of text drawn with its baseline origin at (0, 0), and its advance
+func (t *overlayText) bounds(runs []textRun) (image.Rectangle, int) {
+	var bounds fixed.Rectangle26_6
+	dot := fixed.Point26_6{}
+	for _, run := range runs {
+		runBounds, advance := font.BoundString(run.face, run.text)
+		runBounds = runBounds.Add(dot)
+		if bounds.Empty() {
+			bounds = runBounds
+		} else {
+			bounds = bounds.Union(runBounds)
+		}
+		dot.X += advance
+	}
+
+	rect := image.Rect(bounds.Min.X.Floor(), bounds.Min.Y.Floor(), bounds.Max.X.Ceil(), bounds.Max.Y.Ceil())
+	return rect, dot.X.Ceil()
+}
+
+// Metrics returns the ascent and descent of lines at a pixel size, from the primary font
+func (t *overlayText) Metrics(size int) (ascent, descent int) {
+	for _, face := range t.facesForSize(size) {
+		if face != nil {
+			metrics := face.Metrics()
+			return metrics.Ascent.Ceil(), metrics.Descent.Ceil()
+		}
+	}
+	return size, 0
+}
+
+// Measure returns the advance width of text at a pixel size
+func (t *overlayText) Measure(text string, size int) int {
+	_, advance := t.bounds(t.runs(text, size))
+	return advance
+}
+
+// Draw draws text with its baseline starting at origin
+func (t *overlayText) Draw(frame *gocv.Mat, text string, origin image.Point, size int, style TextStyle) error {
+	runs := t.runs(text, size)
+	if len(runs) == 0 {
+		return nil
+	}
+
+	pixels, err := newFramePixels(frame)
+	if err != nil {
+		return err
+	}
+
+	inkBounds, advance := t.bounds(runs)
+
+	if style.Background {
+		metrics := runs[0].face.Metrics()
+		box := image.Rect(0, -metrics.Ascent.Ceil(), advance, metrics.Descent.Ceil()).
+			Inset(-style.Padding).Add(origin)
+		pixels.blend(box, nil, image.Point{}, style.BackgroundColor, style.BackgroundAlpha)
+	}
+
+	if inkBounds.Empty() {
+		return nil
+	}
+
+	// Rasterize the glyph coverage once, then composite it for the shadow and the text
+	mask := image.NewAlpha(image.Rect(0, 0, inkBounds.Dx(), inkBounds.Dy()))
+	drawer := font.Drawer{
+		Dst: mask,
+		Src: image.Opaque,
+		Dot: fixed.P(-inkBounds.Min.X, -inkBounds.Min.Y),
+	}
+	for _, run := range runs {
+		drawer.Face = run.face
+		drawer.DrawString(run.text)
+	}
+
+	target := inkBounds.Add(origin)
+	if style.Shadow && !style.Background {
+		shadowColor := color.RGBA{R: ShadowColorR, G: ShadowColorG, B: ShadowColorB, A: ShadowColorA}
+		pixels.blend(target.Add(image.Pt(style.ShadowOffset, style.ShadowOffset)), mask, image.Point{}, shadowColor, 1)
+	}
+	pixels.blend(target, mask, image.Point{}, style.Color, 1)
+	return nil
+}
+
+// ----------------------------------------------------------------------
+
+// framePixels gives direct access to the pixels of a continuous 8-bit BGR or BGRA frame
+type framePixels struct {
+	data     []uint8
+	cols     int
+	rows     int
+	channels int
+}
+
+func newFramePixels(frame *gocv.Mat) (*framePixels, error) {
+	if frame.Type() != gocv.MatTypeCV8UC3 && frame.Type() != gocv.MatTypeCV8UC4 {
+		return nil, fmt.Errorf("unsupported frame type for overlay text: %v", frame.Type())
+	}
+
+	data, err := frame.DataPtrUint8()
+	if err != nil {
+		return nil, fmt.Errorf("failed to access frame pixels: %w", err)
+	}
+
+	return &framePixels{data: data, cols: frame.Cols(), rows: frame.Rows(), channels: frame.Channels()}, nil
+}
+
+// blend composites c over rect at opacity; with a mask, each pixel is further weighted by the mask
+// coverage, read starting at maskOrigin
+func (p *framePixels) blend(rect image.Rectangle, mask *image.Alpha, maskOrigin image.Point, c color.RGBA, opacity float64) {
+	clipped := rect.Intersect(image.Rect(0, 0, p.cols, p.rows))
+	if clipped.Empty() || opacity <= 0 {
+		return
+	}
+	if opacity > 1 {
+		opacity = 1
+	}
+
+	weight := uint32(opacity * 255)
+	for y := clipped.Min.Y; y < clipped.Max.Y; y++ {
+		row := y * p.cols * p.channels
+		for x := clipped.Min.X; x < clipped.Max.X; x++ {
+			alpha := weight
+			if mask != nil {
+				coverage := mask.AlphaAt(maskOrigin.X+x-rect.Min.X, maskOrigin.Y+y-rect.Min.Y).A
+				alpha = alpha * uint32(coverage) / 255
+			}
+			if alpha == 0 {
+				continue
+			}
+
+			i := row + x*p.channels
+			p.data[i] = blendChannel(p.data[i], c.B, alpha)
+			p.data[i+1] = blendChannel(p.data[i+1], c.G, alpha)
+			p.data[i+2] = blendChannel(p.data[i+2], c.R, alpha)
+		}
+	}
+}
+
+// composite draws a premultiplied RGBA image with its top-left corner at origin, scaled by opacity
+func (p *framePixels) composite(img *image.RGBA, origin image.Point, opacity float64) {
+	rect := img.Bounds().Sub(img.Bounds().Min).Add(origin)
+	clipped := rect.Intersect(image.Rect(0, 0, p.cols, p.rows))
+	if clipped.Empty() || opacity <= 0 {
+		return
+	}
+	if opacity > 1 {
+		opacity = 1
+	}
+
+	weight := uint32(opacity * 255)
+	for y := clipped.Min.Y; y < clipped.Max.Y; y++ {
+		row := y * p.cols * p.channels
+		for x := clipped.Min.X; x < clipped.Max.X; x++ {
+			src := img.RGBAAt(img.Bounds().Min.X+x-rect.Min.X, img.Bounds().Min.Y+y-rect.Min.Y)
+			alpha := uint32(src.A) * weight / 255
+			if alpha == 0 {
+				continue
+			}
+
+			i := row + x*p.channels
+			p.data[i] = compositeChannel(p.data[i], src.B, weight, alpha)
+			p.data[i+1] = compositeChannel(p.data[i+1], src.G, weight, alpha)
+			p.data[i+2] = compositeChannel(p.data[i+2], src.R, weight, alpha)
+		}
+	}
+}
+
+func blendChannel(dst, src uint8, alpha uint32) uint8 {
+	return uint8((uint32(src)*alpha + uint32(dst)*(255-alpha) + 127) / 255)
+}
+
+// compositeChannel composites a premultiplied source channel scaled by weight over dst
+func compositeChannel(dst, src uint8, weight, alpha uint32) uint8 {
+	return uint8((uint32(src)*weight + uint32(dst)*(255-alpha) + 127) / 255)
+}
@@ -0,0 +1,228 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"worker-service/internal/models"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/f64"
+	"golang.org/x/image/math/fixed"
+)
+
+// ----------------------------------------------------------------------
+
+// Image overlay constants
+const (
+	DefaultLogoScale = 0.1 // logo height as a fraction of the frame height
+
+	// Share of the frame diagonal the watermark text spans
+	WatermarkDiagonalFill = 0.7
+
+	// Font size the watermark is measured at to pick the size it is rasterized at, and the largest raster size
+	watermarkMeasureSize   = 96
+	watermarkMaxRasterSize = 512
+)
+
+// ----------------------------------------------------------------------
+
+// overlayImages composites the logo and the diagonal text watermark. The logo is decoded when configured;
+// the scaled logo and the rotated watermark are cached for the last frame size so drawing them costs a
+// single blend per frame. Like overlayText it is owned by one overlay renderer.
+type overlayImages struct {
+	logoDir string
+	logo    image.Image
+	logoKey logoCacheKey
+	scaled  *image.RGBA
+
+	watermarkKey   watermarkCacheKey
+	watermarkMask  *image.Alpha
+	watermarkSpans []image.Rectangle
+}
+
+// logoCacheKey identifies a scaled logo
+type logoCacheKey struct {
+	frameHeight int
+	scale       float64
+}
+
+// watermarkCacheKey identifies a rotated watermark
+type watermarkCacheKey struct {
+	text      string
+	frameSize image.Point
+}
+
+func newOverlayImages(logoDir string) *overlayImages {
+	return &overlayImages{logoDir: logoDir}
+}
+
+// ----------------------------------------------------------------------
+
+// loadLogo decodes a PNG logo from the logo directory; an empty name removes the logo
+func (o *overlayImages) loadLogo(name string) (image.Image, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if o.logoDir == "" {
+		return nil, invalidParameterError("logo overlays are not configured on this worker")
+	}
+	if filepath.Base(name) != name || !strings.EqualFold(filepath.Ext(name), ".png") {
+		return nil, invalidParameterError("logo file must be the name of a PNG file in the logo directory")
+	}
+
+	file, err := os.Open(filepath.Join(o.logoDir, name))
+	if err != nil {
+		return nil, notFoundError("logo %s not found", name)
+	}
+	defer file.Close()
+
+	logo, err := png.Decode(file)
+	if err != nil {
+		return nil, invalidParameterError("invalid logo %s: %v", name, err)
+	}
+	return logo, nil
+}
+
+// setLogo replaces the decoded logo and drops the scaled copy
+func (o *overlayImages) setLogo(logo image.Image) {
+	o.logo = logo
+	o.scaled = nil
+	o.logoKey = logoCacheKey{}
+}
+
+// drawLogo composites the logo at its anchor, scaled to a share of the frame height
+func (o *overlayImages) drawLogo(pixels *framePixels, config *models.OverlayConfig, layout overlayLayout) {
+	if o.logo == nil || config.LogoOpacity <= 0 {
+		return
+	}
+
+	scale := config.LogoScale
+	if scale <= 0 {
+		scale = DefaultLogoScale
+	}
+
+	key := logoCacheKey{frameHeight: layout.height, scale: scale}
+	if o.scaled == nil || o.logoKey != key {
+		bounds := o.logo.Bounds()
+		height := max(1, int(float64(layout.height)*scale+0.5))
+		width := max(1, bounds.Dx()*height/max(1, bounds.Dy()))
+
+		o.scaled = image.NewRGBA(image.Rect(0, 0, width, height))
+		draw.CatmullRom.Scale(o.scaled, o.scaled.Bounds(), o.logo, bounds, draw.Src, nil)
+		o.logoKey = key
+	}
+
+	margin := layout.px(LayoutMargin)
+	size := o.scaled.Bounds().Size()
+	origin := image.Pt(margin, margin)
+
+	switch anchorOrDefault(config.LogoAnchor, models.AnchorTopRight) {
+	case models.AnchorTopRight:
+		origin.X = layout.width - margin - size.X
+	case models.AnchorBottomLeft:
+		origin.Y = layout.height - margin - size.Y
+	case models.AnchorBottomRight:
+		origin = image.Pt(layout.width-margin-size.X, layout.height-margin-size.Y)
+	}
+
+	pixels.composite(o.scaled, origin, config.LogoOpacity)
+}
+
+// drawWatermark blends the watermark text diagonally across the frame center
+func (o *overlayImages) drawWatermark(pixels *framePixels, text *overlayText, config *models.OverlayConfig, layout overlayLayout, style TextStyle) {
+	if config.WatermarkText == "" || config.WatermarkOpacity <= 0 {
+		return
+	}
+
+	key := watermarkCacheKey{text: config.WatermarkText, frameSize: image.Pt(layout.width, layout.height)}
+	if o.watermarkKey != key {
+		o.watermarkMask = rotatedWatermark(text, config.WatermarkText, layout.width, layout.height)
+		o.watermarkSpans = maskSpans(o.watermarkMask)
+		o.watermarkKey = key
+	}
+
+	for _, span := range o.watermarkSpans {
+		pixels.blend(span, o.watermarkMask, span.Min, style.Color, config.WatermarkOpacity)
+	}
+}
+
+// rotatedWatermark rasterizes text once and rotates it onto a frame-sized mask along the frame diagonal,
+// sized so the text spans WatermarkDiagonalFill of the diagonal
+func rotatedWatermark(text *overlayText, watermark string, width, height int) *image.Alpha {
+	diagonal := math.Hypot(float64(width), float64(height))
+	target := diagonal * WatermarkDiagonalFill
+
+	// Rasterize close to the final size so the rotation barely rescales the glyphs
+	measured := text.Measure(watermark, watermarkMeasureSize)
+	if measured <= 0 {
+		return nil
+	}
+	size := min(watermarkMaxRasterSize, max(LayoutMinFontSize, int(watermarkMeasureSize*target/float64(measured))))
+
+	runs := text.runs(watermark, size)
+	if len(runs) == 0 {
+		return nil
+	}
+
+	inkBounds, _ := text.bounds(runs)
+	if inkBounds.Empty() {
+		return nil
+	}
+
+	source := image.NewAlpha(image.Rect(0, 0, inkBounds.Dx(), inkBounds.Dy()))
+	drawer := font.Drawer{Dst: source, Src: image.Opaque, Dot: fixed.P(-inkBounds.Min.X, -inkBounds.Min.Y)}
+	for _, run := range runs {
+		drawer.Face = run.face
+		drawer.DrawString(run.text)
+	}
+
+	// Map the text onto the frame: center it on the origin, scale and rotate it, then move it to the frame center
+	scale := target / float64(source.Bounds().Dx())
+	angle := -math.Atan2(float64(height), float64(width))
+	cos, sin := math.Cos(angle)*scale, math.Sin(angle)*scale
+
+	cx, cy := float64(width)/2, float64(height)/2
+	sx, sy := float64(source.Bounds().Dx())/2, float64(source.Bounds().Dy())/2
+	transform := f64.Aff3{
+		cos, -sin, cx - cos*sx + sin*sy,
+		sin, cos, cy - sin*sx - cos*sy,
+	}
+
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	draw.BiLinear.Transform(mask, transform, source, source.Bounds(), draw.Src, nil)
+	return mask
+}
+
+// maskSpans returns, for each row of a mask, the range of columns with any coverage, so blending
+// skips the empty parts of the frame
+func maskSpans(mask *image.Alpha) []image.Rectangle {
+	if mask == nil {
+		return nil
+	}
+
+	var spans []image.Rectangle
+	bounds := mask.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		first, last := -1, -1
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if mask.AlphaAt(x, y).A == 0 {
+				continue
+			}
+			if first < 0 {
+				first = x
+			}
+			last = x
+		}
+		if first >= 0 {
+			spans = append(spans, image.Rect(first, y, last+1, y+1))
+		}
+	}
+	return spans
+}
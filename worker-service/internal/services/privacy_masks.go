@@ -0,0 +1,156 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"image"
+	"image/color"
+	"sync"
+	"worker-service/internal/models"
+
+	"gocv.io/x/gocv"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	// privacyPixelBlockSize is the edge length in pixels of one block of a pixelated mask
+	privacyPixelBlockSize = 16
+)
+
+// privacyFillColor is the color of filled masks
+var privacyFillColor = color.RGBA{R: 0, G: 0, B: 0, A: FullAlpha}
+
+// ----------------------------------------------------------------------
+
+// PrivacyMasker blanks out the privacy mask polygons of a camera.
+// It runs on every processed frame before anything else sees it, so masked areas never reach
+// the published stream, snapshots, clips, processed recordings or face detection.
+type PrivacyMasker struct {
+	mutex sync.RWMutex
+	masks []models.PrivacyMask
+}
+
+// NewPrivacyMasker creates a privacy masker with no masks
+func NewPrivacyMasker() *PrivacyMasker {
+	return &PrivacyMasker{}
+}
+
+// ----------------------------------------------------------------------
+
+// SetMasks validates and replaces the masks; masks without a mode are filled
+func (m *PrivacyMasker) SetMasks(masks []models.PrivacyMask) error {
+	names := make(map[string]bool, len(masks))
+	normalized := make([]models.PrivacyMask, len(masks))
+	for i, mask := range masks {
+		if names[mask.Name] {
+			return invalidParameterError("duplicate privacy mask name %q", mask.Name)
+		}
+		names[mask.Name] = true
+
+		if mask.Mode == "" {
+			mask.Mode = models.PrivacyMaskModeFill
+		}
+		if mask.Mode != models.PrivacyMaskModeFill && mask.Mode != models.PrivacyMaskModePixelate {
+			return invalidParameterError("privacy mask %q: mode must be %q or %q", mask.Name, models.PrivacyMaskModeFill, models.PrivacyMaskModePixelate)
+		}
+		if len(mask.Points) < 3 {
+			return invalidParameterError("privacy mask %q: a polygon needs at least 3 points", mask.Name)
+		}
+		normalized[i] = mask
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.masks = normalized
+	return nil
+}
+
+// Masks returns the current masks
+func (m *PrivacyMasker) Masks() []models.PrivacyMask {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return append([]models.PrivacyMask(nil), m.masks...)
+}
+
+// Apply blanks out every mask on the frame in place
+func (m *PrivacyMasker) Apply(frame *gocv.Mat) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if len(m.masks) == 0 || frame.Empty() {
+		return
+	}
+
+	for _, mask := range m.masks {
+		points := polygonPixels(mask.Points, frame.Cols(), frame.Rows())
+		if mask.Mode == models.PrivacyMaskModePixelate {
+			pixelatePolygon(frame, points)
+		} else {
+			fillPolygon(frame, points)
+		}
+	}
+}
+
+// ----------------------------------------------------------------------
+
+func fillPolygon(frame *gocv.Mat, points []image.Point) {
+	polygon := gocv.NewPointsVectorFromPoints([][]image.Point{points})
+	defer polygon.Close()
+
+	gocv.FillPoly(frame, polygon, privacyFillColor)
+}
+
+// pixelatePolygon pixelates the polygon's bounding box and copies the result back through a polygon mask
+func pixelatePolygon(frame *gocv.Mat, points []image.Point) {
+	bounds := polygonBounds(points).Intersect(image.Rect(0, 0, frame.Cols(), frame.Rows()))
+	if bounds.Empty() {
+		return
+	}
+
+	region := frame.Region(bounds)
+	defer region.Close()
+
+	pixelated := pixelate(region, image.Pt(bounds.Dx()/privacyPixelBlockSize, bounds.Dy()/privacyPixelBlockSize))
+	defer pixelated.Close()
+
+	shifted := make([]image.Point, len(points))
+	for i, point := range points {
+		shifted[i] = point.Sub(bounds.Min)
+	}
+
+	polygonMask := gocv.NewMatWithSize(bounds.Dy(), bounds.Dx(), gocv.MatTypeCV8UC1)
+	defer polygonMask.Close()
+	polygonMask.SetTo(gocv.NewScalar(0, 0, 0, 0))
+
+	polygon := gocv.NewPointsVectorFromPoints([][]image.Point{shifted})
+	defer polygon.Close()
+	gocv.FillPoly(&polygonMask, polygon, color.RGBA{R: 255, G: 255, B: 255, A: FullAlpha})
+
+	pixelated.CopyToWithMask(&region, polygonMask)
+}
+
+// pixelate returns a copy of src reduced to the given number of blocks per axis; the caller closes it
+func pixelate(src gocv.Mat, blocks image.Point) gocv.Mat {
+	small := gocv.NewMat()
+	defer small.Close()
+	gocv.Resize(src, &small, image.Pt(max(1, blocks.X), max(1, blocks.Y)), 0, 0, gocv.InterpolationArea)
+
+	pixelated := gocv.NewMat()
+	gocv.Resize(small, &pixelated, image.Pt(src.Cols(), src.Rows()), 0, 0, gocv.InterpolationNearestNeighbor)
+	return pixelated
+}
+
+// polygonBounds returns the smallest rectangle containing every point
+func polygonBounds(points []image.Point) image.Rectangle {
+	bounds := image.Rectangle{Min: points[0], Max: points[0]}
+	for _, point := range points[1:] {
+		bounds.Min.X = min(bounds.Min.X, point.X)
+		bounds.Min.Y = min(bounds.Min.Y, point.Y)
+		bounds.Max.X = max(bounds.Max.X, point.X)
+		bounds.Max.Y = max(bounds.Max.Y, point.Y)
+	}
+	bounds.Max = bounds.Max.Add(image.Pt(1, 1))
+	return bounds
+}
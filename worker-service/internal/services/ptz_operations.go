@@ -0,0 +1,121 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"net/url"
+	"time"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+// ControlPTZ executes a PTZ command against the camera of a running stream
+func (sm *StreamManager) ControlPTZ(cameraID string, req *models.PTZControlRequest) (*models.PTZControlResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.ptzClient == nil {
+		return nil, invalidParameterError("PTZ is not configured for camera %s", cameraID)
+	}
+
+	if err := validatePTZRequest(req); err != nil {
+		return nil, err
+	}
+
+	utils.GetLogger().Infof("PTZ %s for camera %s (pan=%.2f tilt=%.2f zoom=%.2f preset=%q)",
+		req.Action, cameraID, req.Pan, req.Tilt, req.Zoom, req.PresetToken)
+
+	client := session.ptzClient
+	switch req.Action {
+	case models.PTZActionContinuousMove:
+		err = client.ContinuousMove(req.Pan, req.Tilt, req.Zoom, time.Duration(req.TimeoutMs)*time.Millisecond)
+	case models.PTZActionStop:
+		err = client.Stop()
+	case models.PTZActionAbsoluteMove:
+		err = client.AbsoluteMove(req.Pan, req.Tilt, req.Zoom, req.Speed)
+	case models.PTZActionRelativeMove:
+		err = client.RelativeMove(req.Pan, req.Tilt, req.Zoom, req.Speed)
+	case models.PTZActionGotoPreset:
+		err = client.GotoPreset(req.PresetToken, req.Speed)
+	}
+
+	if err != nil {
+		return nil, upstreamUnavailableError(err, "PTZ %s failed for camera %s", req.Action, cameraID)
+	}
+
+	return &models.PTZControlResponse{CameraID: cameraID, Action: req.Action}, nil
+}
+
+// GetPTZPresets lists the presets stored on the camera of a running stream
+func (sm *StreamManager) GetPTZPresets(cameraID string) (*models.PTZPresetsResponse, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.ptzClient == nil {
+		return nil, invalidParameterError("PTZ is not configured for camera %s", cameraID)
+	}
+
+	presets, err := session.ptzClient.GetPresets()
+	if err != nil {
+		return nil, upstreamUnavailableError(err, "failed to get PTZ presets for camera %s", cameraID)
+	}
+
+	return &models.PTZPresetsResponse{CameraID: cameraID, Presets: presets}, nil
+}
+
+// ----------------------------------------------------------------------
+
+// validatePTZRequest checks the command values against the ONVIF generic spaces
+func validatePTZRequest(req *models.PTZControlRequest) error {
+	inRange := func(value, min, max float64) bool {
+		return value >= min && value <= max
+	}
+
+	switch req.Action {
+	case models.PTZActionContinuousMove, models.PTZActionRelativeMove:
+		if !inRange(req.Pan, -1, 1) || !inRange(req.Tilt, -1, 1) || !inRange(req.Zoom, -1, 1) {
+			return invalidParameterError("pan, tilt and zoom must be between -1 and 1")
+		}
+	case models.PTZActionAbsoluteMove:
+		if !inRange(req.Pan, -1, 1) || !inRange(req.Tilt, -1, 1) || !inRange(req.Zoom, 0, 1) {
+			return invalidParameterError("pan and tilt must be between -1 and 1, zoom between 0 and 1")
+		}
+	case models.PTZActionGotoPreset:
+		if req.PresetToken == "" {
+			return invalidParameterError("presetToken is required for gotoPreset")
+		}
+	}
+
+	if req.Speed != nil && !inRange(*req.Speed, 0, 1) {
+		return invalidParameterError("speed must be between 0 and 1")
+	}
+
+	if req.TimeoutMs < 0 {
+		return invalidParameterError("timeoutMs must not be negative")
+	}
+
+	return nil
+}
+
+// onvifConfigWithRTSPCredentials falls back to the RTSP URL credentials when none are given for ONVIF
+func onvifConfigWithRTSPCredentials(cfg *models.ONVIFConfig, rtspUrl string) *models.ONVIFConfig {
+	resolved := *cfg
+	if resolved.Username != "" {
+		return &resolved
+	}
+
+	parsed, err := url.Parse(rtspUrl)
+	if err != nil || parsed.User == nil {
+		return &resolved
+	}
+
+	resolved.Username = parsed.User.Username()
+	resolved.Password, _ = parsed.User.Password()
+	return &resolved
+}
@@ -0,0 +1,275 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"worker-service/internal/config"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	recordingRetentionInterval = 1 * time.Minute
+
+	// Segment names are "<cameraID>_<UTC start>.mp4"; FFmpeg expands the strftime pattern
+	recordingTimeLayout   = "20060102T150405Z"
+	recordingTimePattern  = "%Y%m%dT%H%M%SZ"
+	recordingSegmentExt   = ".mp4"
+	recordingCameraPrefix = "camera_"
+)
+
+// ----------------------------------------------------------------------
+
+// RecordingManager owns the on-disk recording segments of every camera and enforces retention
+type RecordingManager struct {
+	baseDir         string
+	segmentDuration time.Duration
+	retention       time.Duration
+	maxDiskBytes    int64
+	stop            chan struct{}
+}
+
+// recordingFile is a segment found on disk
+type recordingFile struct {
+	cameraID  string
+	path      string
+	startTime time.Time
+	modTime   time.Time
+	size      int64
+}
+
+// NewRecordingManager creates a new recording manager
+func NewRecordingManager(cfg *config.Config) *RecordingManager {
+	return &RecordingManager{
+		baseDir:         cfg.RecordingDir,
+		segmentDuration: time.Duration(cfg.RecordingSegmentSeconds) * time.Second,
+		retention:       time.Duration(cfg.RecordingRetentionHours) * time.Hour,
+		maxDiskBytes:    int64(cfg.RecordingMaxDiskMB) * 1024 * 1024,
+		stop:            make(chan struct{}),
+	}
+}
+
+// ----------------------------------------------------------------------
+
+// Start runs the retention job in the background until Stop is called
+func (rm *RecordingManager) Start() {
+	go func() {
+		ticker := time.NewTicker(recordingRetentionInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-rm.stop:
+				return
+			case <-ticker.C:
+				rm.EnforceRetention()
+			}
+		}
+	}()
+}
+
+// Stop ends the retention job
+func (rm *RecordingManager) Stop() {
+	close(rm.stop)
+}
+
+// PrepareCamera creates the recording directory of a camera
+func (rm *RecordingManager) PrepareCamera(cameraID string) error {
+	if err := rm.checkCameraDir(cameraID); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(rm.cameraDir(cameraID), 0o755); err != nil {
+		return fmt.Errorf("failed to create recording directory: %w", err)
+	}
+	return nil
+}
+
+// TeeOutput returns the FFmpeg tee slave spec that records an already-encoded stream
+func (rm *RecordingManager) TeeOutput(cameraID string) string {
+	options := fmt.Sprintf("segment_time=%d:segment_format=mp4:strftime=1:reset_timestamps=1", int(rm.segmentDuration.Seconds()))
+	return segmentTeeOutput(options, rm.segmentPattern(cameraID))
+}
+
+// ListSegments returns the segments of a camera that overlap [from, to]; zero times leave the range open
+func (rm *RecordingManager) ListSegments(cameraID string, from, to time.Time) ([]models.RecordingSegment, error) {
+	files, err := rm.scanCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]models.RecordingSegment, 0, len(files))
+	for _, file := range files {
+		if !from.IsZero() && file.modTime.Before(from) {
+			continue
+		}
+		if !to.IsZero() && file.startTime.After(to) {
+			continue
+		}
+
+		segments = append(segments, models.RecordingSegment{
+			FileName:        filepath.Base(file.path),
+			StartTime:       file.startTime,
+			EndTime:         file.modTime.UTC(),
+			DurationSeconds: file.modTime.Sub(file.startTime).Seconds(),
+			SizeBytes:       file.size,
+		})
+	}
+
+	return segments, nil
+}
+
+// EnforceRetention deletes segments older than the retention period, then the oldest segments over the disk quota
+func (rm *RecordingManager) EnforceRetention() {
+	logger := utils.GetLogger()
+
+	files, err := rm.scanAll()
+	if err != nil {
+		logger.Warnf("[Recording] Failed to scan recordings: %v", err)
+		return
+	}
+
+	var kept []recordingFile
+	var totalBytes int64
+	removed := 0
+
+	cutoff := time.Now().Add(-rm.retention)
+	for _, file := range files {
+		if rm.retention > 0 && file.modTime.Before(cutoff) {
+			if rm.removeSegment(file) {
+				removed++
+			}
+			continue
+		}
+		kept = append(kept, file)
+		totalBytes += file.size
+	}
+
+	if rm.maxDiskBytes > 0 {
+		// Oldest first across all cameras
+		sort.Slice(kept, func(i, j int) bool {
+			return kept[i].startTime.Before(kept[j].startTime)
+		})
+
+		for _, file := range kept {
+			if totalBytes <= rm.maxDiskBytes {
+				break
+			}
+			if rm.removeSegment(file) {
+				totalBytes -= file.size
+				removed++
+			}
+		}
+	}
+
+	if removed > 0 {
+		logger.Infof("[Recording] Retention removed %d segments (%.1f MB remaining)", removed, float64(totalBytes)/1024/1024)
+	}
+}
+
+// ----------------------------------------------------------------------
+
+func (rm *RecordingManager) cameraDir(cameraID string) string {
+	return filepath.Join(rm.baseDir, recordingCameraPrefix+cameraID)
+}
+
+// checkCameraDir makes sure the recording directory of a camera stays inside the recordings directory
+func (rm *RecordingManager) checkCameraDir(cameraID string) error {
+	if !isChildPath(rm.baseDir, rm.cameraDir(cameraID)) {
+		return fmt.Errorf("recording directory for camera %q is outside %s", cameraID, rm.baseDir)
+	}
+	return nil
+}
+
+func (rm *RecordingManager) segmentPattern(cameraID string) string {
+	return filepath.Join(rm.cameraDir(cameraID), cameraID+"_"+recordingTimePattern+recordingSegmentExt)
+}
+
+// scanAll returns the segments of every camera
+func (rm *RecordingManager) scanAll() ([]recordingFile, error) {
+	entries, err := os.ReadDir(rm.baseDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []recordingFile
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), recordingCameraPrefix) {
+			continue
+		}
+
+		cameraFiles, err := rm.scanCamera(strings.TrimPrefix(entry.Name(), recordingCameraPrefix))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, cameraFiles...)
+	}
+
+	return files, nil
+}
+
+// scanCamera returns the segments of one camera ordered by start time
+func (rm *RecordingManager) scanCamera(cameraID string) ([]recordingFile, error) {
+	if err := rm.checkCameraDir(cameraID); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(rm.cameraDir(cameraID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	prefix := cameraID + "_"
+	var files []recordingFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, recordingSegmentExt) {
+			continue
+		}
+
+		startTime, err := time.Parse(recordingTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, prefix), recordingSegmentExt))
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+
+		files = append(files, recordingFile{
+			cameraID:  cameraID,
+			path:      filepath.Join(rm.cameraDir(cameraID), name),
+			startTime: startTime,
+			modTime:   info.ModTime(),
+			size:      info.Size(),
+		})
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].startTime.Before(files[j].startTime)
+	})
+
+	return files, nil
+}
+
+func (rm *RecordingManager) removeSegment(file recordingFile) bool {
+	if err := os.Remove(file.path); err != nil {
+		utils.GetLogger().Warnf("[Recording] Failed to remove segment %s: %v", file.path, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"worker-service/internal/models"
+)
+
+// ----------------------------------------------------------------------
+
+// ListRecordings lists the recorded segments of a camera; recordings outlive the stream, so it need not be running
+func (sm *StreamManager) ListRecordings(cameraID string, query *models.RecordingsQuery) (*models.RecordingsResponse, error) {
+	if err := validateCameraID(cameraID); err != nil {
+		return nil, err
+	}
+
+	if !query.From.IsZero() && !query.To.IsZero() && query.To.Before(query.From) {
+		return nil, invalidParameterError("to must not be before from")
+	}
+
+	segments, err := sm.recordingManager.ListSegments(cameraID, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.RecordingsResponse{CameraID: cameraID, Segments: segments}, nil
+}
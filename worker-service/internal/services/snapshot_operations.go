@@ -0,0 +1,120 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"fmt"
+	"image"
+	"time"
+	"worker-service/internal/models"
+
+	"gocv.io/x/gocv"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	defaultSnapshotQuality = 85
+)
+
+// ----------------------------------------------------------------------
+
+// FrameSnapshot is an encoded still image taken from a running stream
+type FrameSnapshot struct {
+	Data        []byte
+	ContentType string
+	Width       int
+	Height      int
+	CapturedAt  time.Time
+}
+
+// ----------------------------------------------------------------------
+
+// GetSnapshot encodes the most recent processed frame of a stream
+func (sm *StreamManager) GetSnapshot(cameraID string, query *models.SnapshotQuery) (*FrameSnapshot, error) {
+	session, err := sm.getSession(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeLatestFrame(session, query)
+}
+
+// OpenStoredSnapshot checks a request for a snapshot of the local snapshot store and returns the file to serve
+func (sm *StreamManager) OpenStoredSnapshot(key, expires, signature string) (string, error) {
+	store, ok := sm.alertService.SnapshotStore().(*LocalSnapshotStore)
+	if !ok {
+		return "", notFoundError("snapshots are not served by this worker")
+	}
+
+	return store.Open(key, expires, signature)
+}
+
+// ----------------------------------------------------------------------
+
+// encodeLatestFrame encodes the session's most recent frame according to the query options
+func encodeLatestFrame(session *StreamSession, query *models.SnapshotQuery) (*FrameSnapshot, error) {
+	cameraID := session.CameraID
+
+	annotated := query.Variant != models.SnapshotVariantRaw
+	frame, width, height, capturedAt, ok := session.latestFrame.Snapshot(annotated)
+	if !ok {
+		return nil, notFoundError("no frame available yet for camera %s", cameraID)
+	}
+
+	mat, err := gocv.NewMatFromBytes(height, width, gocv.MatTypeCV8UC3, frame)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build frame for camera %s: %w", cameraID, err)
+	}
+	defer mat.Close()
+
+	// Downscale preserving aspect ratio
+	output := mat
+	if query.MaxWidth > 0 && width > query.MaxWidth {
+		height = height * query.MaxWidth / width
+		width = query.MaxWidth
+
+		resized := gocv.NewMat()
+		defer resized.Close()
+		gocv.Resize(mat, &resized, image.Pt(width, height), 0, 0, gocv.InterpolationArea)
+		output = resized
+	}
+
+	data, contentType, err := encodeFrame(output, query.Format, query.Quality)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FrameSnapshot{
+		Data:        data,
+		ContentType: contentType,
+		Width:       width,
+		Height:      height,
+		CapturedAt:  capturedAt,
+	}, nil
+}
+
+// ----------------------------------------------------------------------
+
+// encodeFrame encodes a frame as JPEG (default) or PNG
+func encodeFrame(mat gocv.Mat, format string, quality int) ([]byte, string, error) {
+	if format == models.SnapshotFormatPNG {
+		buf, err := gocv.IMEncode(gocv.PNGFileExt, mat)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode PNG: %w", err)
+		}
+		defer buf.Close()
+		return copyInto(nil, buf.GetBytes()), "image/png", nil
+	}
+
+	if quality <= 0 {
+		quality = defaultSnapshotQuality
+	}
+
+	buf, err := gocv.IMEncodeWithParams(gocv.JPEGFileExt, mat, []int{gocv.IMWriteJpegQuality, quality})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode JPEG: %w", err)
+	}
+	defer buf.Close()
+	return copyInto(nil, buf.GetBytes()), "image/jpeg", nil
+}
@@ -0,0 +1,92 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"worker-service/internal/config"
+
+	"github.com/google/uuid"
+)
+
+// ----------------------------------------------------------------------
+
+// Snapshot store backends, selected with SNAPSHOT_STORE
+const (
+	SnapshotStoreCloudinary = "cloudinary"
+	SnapshotStoreLocal      = "local"
+	SnapshotStoreS3         = "s3"
+)
+
+// ----------------------------------------------------------------------
+
+// SnapshotStore persists alert snapshots and event clips and returns the key of the stored object.
+// The variant names the image of an alert (empty for the full frame, e.g. "annotated" or "face1").
+// Keys are what gets kept; URLs are built from them when needed, as backends that support it return signed URLs,
+// expiring when SNAPSHOT_URL_EXPIRY_SECONDS is set.
+type SnapshotStore interface {
+	Save(cameraID, variant string, image []byte, capturedAt time.Time) (string, error)
+
+	// SaveClip stores the MP4 clip at path; the caller keeps ownership of the file
+	SaveClip(cameraID, path string, capturedAt time.Time) (string, error)
+
+	// URL returns the URL a stored object can be fetched from, signed from now on
+	URL(key string) (string, error)
+}
+
+// NewSnapshotStore creates the snapshot store selected by the configuration
+func NewSnapshotStore(cfg *config.Config) (SnapshotStore, error) {
+	switch cfg.SnapshotStore {
+	case SnapshotStoreLocal:
+		store, err := NewLocalSnapshotStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case SnapshotStoreS3:
+		store, err := NewS3SnapshotStore(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case SnapshotStoreCloudinary:
+		cld := newCloudinaryClient(cfg)
+		if cld == nil {
+			return nil, fmt.Errorf("cloudinary credentials not configured")
+		}
+		return NewCloudinarySnapshotStore(cfg, cld), nil
+	default:
+		return nil, fmt.Errorf("unknown snapshot store %q", cfg.SnapshotStore)
+	}
+}
+
+// Extensions of the stored objects
+const (
+	snapshotExt = ".jpg"
+	clipExt     = ".mp4"
+)
+
+// clipVariant names event clips in object keys
+const clipVariant = "clip"
+
+// snapshotObjectKey names a stored object: one folder per camera, timestamped and suffixed with the variant
+// and a random part so objects stored in the same second never overwrite each other
+func snapshotObjectKey(cameraID, variant, ext string, capturedAt time.Time) string {
+	camera := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r < 32 {
+			return '_'
+		}
+		return r
+	}, cameraID)
+	if camera == "." || camera == ".." {
+		camera = "_"
+	}
+
+	name := capturedAt.UTC().Format("20060102_150405")
+	if variant != "" {
+		name += "_" + variant
+	}
+	return fmt.Sprintf("%s/%s_%s%s", camera, name, uuid.New().String()[:8], ext)
+}
@@ -0,0 +1,144 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"time"
+	"worker-service/internal/config"
+	"worker-service/internal/utils"
+
+	"github.com/cloudinary/cloudinary-go/v2"
+	"github.com/cloudinary/cloudinary-go/v2/api"
+	"github.com/cloudinary/cloudinary-go/v2/api/uploader"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	cloudinaryUploadTimeout     = 10 * time.Second
+	cloudinaryClipUploadTimeout = 60 * time.Second
+)
+
+// ----------------------------------------------------------------------
+
+// CloudinarySnapshotStore uploads snapshots and clips to Cloudinary.
+// Signed snapshots are uploaded as authenticated assets and delivered through signed URLs, which do not expire.
+type CloudinarySnapshotStore struct {
+	client *cloudinary.Cloudinary
+	folder string
+	signed bool
+}
+
+// NewCloudinarySnapshotStore creates a Cloudinary snapshot store on an initialized client
+func NewCloudinarySnapshotStore(cfg *config.Config, client *cloudinary.Cloudinary) *CloudinarySnapshotStore {
+	if cfg.SnapshotSignedURLs && cfg.SnapshotURLExpirySeconds > 0 {
+		utils.GetLogger().Warn("⚠️ Cloudinary snapshot URLs are signed but cannot expire; SNAPSHOT_URL_EXPIRY_SECONDS is ignored")
+	}
+
+	return &CloudinarySnapshotStore{
+		client: client,
+		folder: cfg.CloudinaryFolder,
+		signed: cfg.SnapshotSignedURLs,
+	}
+}
+
+// newCloudinaryClient initializes the Cloudinary client, or returns nil without credentials
+func newCloudinaryClient(cfg *config.Config) *cloudinary.Cloudinary {
+	if cfg.CloudinaryCloudName == "" || cfg.CloudinaryAPIKey == "" || cfg.CloudinaryAPISecret == "" {
+		return nil
+	}
+
+	cld, err := cloudinary.NewFromParams(cfg.CloudinaryCloudName, cfg.CloudinaryAPIKey, cfg.CloudinaryAPISecret)
+	if err != nil {
+		utils.GetLogger().Warnf("Failed to initialize Cloudinary: %v", err)
+		return nil
+	}
+	return cld
+}
+
+// ----------------------------------------------------------------------
+
+// Save uploads a JPEG snapshot and returns its key, the public ID with the format
+func (s *CloudinarySnapshotStore) Save(cameraID, variant string, image []byte, capturedAt time.Time) (string, error) {
+	timestamp := capturedAt.Format("20060102_150405")
+	publicID := fmt.Sprintf("%s/%s_%s", s.folder, cameraID, timestamp)
+	if variant != "" {
+		publicID += "_" + variant
+	}
+
+	params := uploader.UploadParams{
+		PublicID:       publicID,
+		ResourceType:   "image",
+		Format:         "jpg",
+		Transformation: "q_auto:low,f_auto",
+	}
+	if s.signed {
+		params.Type = api.Authenticated
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cloudinaryUploadTimeout)
+	defer cancel()
+
+	uploadResult, err := s.client.Upload.Upload(ctx, bytes.NewReader(image), params)
+	if err != nil {
+		return "", fmt.Errorf("cloudinary upload failed: %w", err)
+	}
+	if uploadResult.Error.Message != "" {
+		return "", fmt.Errorf("cloudinary upload failed: %s", uploadResult.Error.Message)
+	}
+
+	return uploadResult.PublicID + "." + uploadResult.Format, nil
+}
+
+// SaveClip uploads an MP4 event clip as a video asset and returns its key
+func (s *CloudinarySnapshotStore) SaveClip(cameraID, path string, capturedAt time.Time) (string, error) {
+	timestamp := capturedAt.Format("20060102_150405")
+	params := uploader.UploadParams{
+		PublicID:     fmt.Sprintf("%s/clips/%s_%s", s.folder, cameraID, timestamp),
+		ResourceType: "video",
+	}
+	if s.signed {
+		params.Type = api.Authenticated
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cloudinaryClipUploadTimeout)
+	defer cancel()
+
+	uploadResult, err := s.client.Upload.Upload(ctx, path, params)
+	if err != nil {
+		return "", fmt.Errorf("cloudinary clip upload failed: %w", err)
+	}
+	if uploadResult.Error.Message != "" {
+		return "", fmt.Errorf("cloudinary clip upload failed: %s", uploadResult.Error.Message)
+	}
+
+	return uploadResult.PublicID + "." + uploadResult.Format, nil
+}
+
+// URL returns the HTTPS delivery URL of an asset, a signed URL for authenticated assets
+func (s *CloudinarySnapshotStore) URL(key string) (string, error) {
+	newAsset := s.client.Image
+	if path.Ext(key) == clipExt {
+		newAsset = s.client.Video
+	}
+
+	delivered, err := newAsset(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to build delivery URL: %w", err)
+	}
+	delivered.Config.URL.Secure = true
+	if s.signed {
+		delivered.DeliveryType = api.Authenticated
+		delivered.Config.URL.SignURL = true
+	}
+
+	url, err := delivered.String()
+	if err != nil {
+		return "", fmt.Errorf("failed to build delivery URL: %w", err)
+	}
+	return url, nil
+}
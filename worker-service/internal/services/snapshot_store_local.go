@@ -0,0 +1,165 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"worker-service/internal/config"
+)
+
+// ----------------------------------------------------------------------
+
+// localSnapshotRoute is the public route the worker serves stored snapshots from
+const localSnapshotRoute = "/snapshots/"
+
+// ----------------------------------------------------------------------
+
+// LocalSnapshotStore writes snapshots and clips to a local directory and serves them back through the worker.
+// With a signing key, URLs carry an HMAC signature and, when configured, an expiry.
+type LocalSnapshotStore struct {
+	dir        string
+	baseURL    string
+	signingKey []byte
+	expiry     time.Duration
+}
+
+// NewLocalSnapshotStore creates the snapshot directory
+func NewLocalSnapshotStore(cfg *config.Config) (*LocalSnapshotStore, error) {
+	if err := os.MkdirAll(cfg.SnapshotLocalDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+
+	store := &LocalSnapshotStore{
+		dir:     cfg.SnapshotLocalDir,
+		baseURL: strings.TrimRight(cfg.SnapshotPublicBaseURL, "/"),
+		expiry:  time.Duration(cfg.SnapshotURLExpirySeconds) * time.Second,
+	}
+	if cfg.SnapshotSignedURLs {
+		store.signingKey = []byte(cfg.SnapshotSigningKey)
+	}
+	return store, nil
+}
+
+// ----------------------------------------------------------------------
+
+// Save writes a JPEG snapshot and returns its key
+func (s *LocalSnapshotStore) Save(cameraID, variant string, image []byte, capturedAt time.Time) (string, error) {
+	key := snapshotObjectKey(cameraID, variant, snapshotExt, capturedAt)
+	if err := s.write(key, bytes.NewReader(image)); err != nil {
+		return "", fmt.Errorf("failed to write snapshot: %w", err)
+	}
+	return key, nil
+}
+
+// SaveClip copies an event clip into the store and returns its key
+func (s *LocalSnapshotStore) SaveClip(cameraID, path string, capturedAt time.Time) (string, error) {
+	clip, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open clip: %w", err)
+	}
+	defer clip.Close()
+
+	key := snapshotObjectKey(cameraID, clipVariant, clipExt, capturedAt)
+	if err := s.write(key, clip); err != nil {
+		return "", fmt.Errorf("failed to write clip: %w", err)
+	}
+	return key, nil
+}
+
+// URL returns the URL the worker serves a stored object at
+func (s *LocalSnapshotStore) URL(key string) (string, error) {
+	return s.url(key, time.Now()), nil
+}
+
+// write stores an object under its key; it is written then renamed so it is never served half-written
+func (s *LocalSnapshotStore) write(key string, content io.Reader) error {
+	filePath := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return err
+	}
+
+	tmpPath := filePath + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, filePath)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Open checks a snapshot request and returns the file to serve
+func (s *LocalSnapshotStore) Open(key, expires, signature string) (string, error) {
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || key != path.Clean(key) || key == ".." || strings.HasPrefix(key, "../") || strings.Contains(key, `\`) {
+		return "", invalidParameterError("invalid snapshot key")
+	}
+
+	if s.signingKey != nil {
+		if !hmac.Equal([]byte(signature), []byte(s.sign(key, expires))) {
+			return "", forbiddenError("invalid snapshot signature")
+		}
+		if expires != "" {
+			expiresAt, err := strconv.ParseInt(expires, 10, 64)
+			if err != nil || time.Now().Unix() > expiresAt {
+				return "", forbiddenError("snapshot URL expired")
+			}
+		}
+	}
+
+	filePath := filepath.Join(s.dir, filepath.FromSlash(key))
+	if info, err := os.Stat(filePath); err != nil || info.IsDir() {
+		return "", notFoundError("snapshot %s not found", key)
+	}
+	return filePath, nil
+}
+
+// url builds the public URL of a key, signed when a signing key is configured
+func (s *LocalSnapshotStore) url(key string, now time.Time) string {
+	escaped := make([]string, 0, strings.Count(key, "/")+1)
+	for _, segment := range strings.Split(key, "/") {
+		escaped = append(escaped, url.PathEscape(segment))
+	}
+	snapshotURL := s.baseURL + localSnapshotRoute + strings.Join(escaped, "/")
+
+	if s.signingKey == nil {
+		return snapshotURL
+	}
+
+	query := url.Values{}
+	expires := ""
+	if s.expiry > 0 {
+		expires = strconv.FormatInt(now.Add(s.expiry).Unix(), 10)
+		query.Set("expires", expires)
+	}
+	query.Set("signature", s.sign(key, expires))
+	return snapshotURL + "?" + query.Encode()
+}
+
+// sign returns the hex HMAC-SHA256 of a key and its expiry
+func (s *LocalSnapshotStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,175 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"worker-service/internal/config"
+)
+
+// ----------------------------------------------------------------------
+
+func newTestLocalStore(t *testing.T, signed bool, expirySeconds int) *LocalSnapshotStore {
+	t.Helper()
+
+	store, err := NewLocalSnapshotStore(&config.Config{
+		SnapshotLocalDir:         t.TempDir(),
+		SnapshotPublicBaseURL:    "http://worker:8080/",
+		SnapshotSignedURLs:       signed,
+		SnapshotSigningKey:       "test-signing-key",
+		SnapshotURLExpirySeconds: expirySeconds,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+// parseSnapshotURL splits a snapshot URL into the parameters the snapshot route passes to Open
+func parseSnapshotURL(t *testing.T, snapshotURL string) (key, expires, signature string) {
+	t.Helper()
+
+	u, err := url.Parse(snapshotURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, found := strings.CutPrefix(u.Path, localSnapshotRoute)
+	if !found {
+		t.Fatalf("snapshot URL %s is not under %s", snapshotURL, localSnapshotRoute)
+	}
+	return key, u.Query().Get("expires"), u.Query().Get("signature")
+}
+
+// ----------------------------------------------------------------------
+
+func TestLocalSnapshotStoreSaveAndOpen(t *testing.T) {
+	store := newTestLocalStore(t, true, 60)
+
+	key, err := store.Save("cam-1", "annotated", []byte("jpeg"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(key, "cam-1/") || strings.Contains(key, "?") {
+		t.Errorf("snapshot key = %s", key)
+	}
+
+	snapshotURL, _ := store.URL(key)
+	if !strings.HasPrefix(snapshotURL, "http://worker:8080/snapshots/"+key+"?") {
+		t.Errorf("snapshot URL = %s", snapshotURL)
+	}
+
+	filePath, err := store.Open(parseSnapshotURL(t, snapshotURL))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if data, err := os.ReadFile(filePath); err != nil || string(data) != "jpeg" {
+		t.Errorf("served file = %q, %v", data, err)
+	}
+}
+
+func TestLocalSnapshotStoreSaveClip(t *testing.T) {
+	store := newTestLocalStore(t, false, 0)
+
+	clipPath := filepath.Join(t.TempDir(), "event.mp4")
+	if err := os.WriteFile(clipPath, []byte("mp4"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := store.SaveClip("cam-1", clipPath, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(key, clipExt) {
+		t.Errorf("clip key = %s, want a %s object", key, clipExt)
+	}
+
+	clipURL, _ := store.URL(key)
+	filePath, err := store.Open(parseSnapshotURL(t, clipURL))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if data, err := os.ReadFile(filePath); err != nil || string(data) != "mp4" {
+		t.Errorf("served clip = %q, %v", data, err)
+	}
+	if _, err := os.Stat(clipPath); err != nil {
+		t.Errorf("source clip removed: %v", err)
+	}
+}
+
+func TestLocalSnapshotStoreOpenRejectsTraversal(t *testing.T) {
+	store := newTestLocalStore(t, false, 0)
+
+	for _, key := range []string{"..", "../secret", "cam/../../secret", `cam\..\secret`, "cam//a.jpg", ""} {
+		if _, err := store.Open(key, "", ""); !errors.Is(err, ErrInvalidParameter) {
+			t.Errorf("Open(%q) = %v, want an invalid parameter error", key, err)
+		}
+	}
+}
+
+func TestLocalSnapshotStoreOpenRejectsBadSignature(t *testing.T) {
+	store := newTestLocalStore(t, true, 0)
+
+	saved, err := store.Save("cam-1", "", []byte("jpeg"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotURL, _ := store.URL(saved)
+	key, expires, signature := parseSnapshotURL(t, snapshotURL)
+
+	for name, signature := range map[string]string{
+		"missing":  "",
+		"tampered": strings.Repeat("0", len(signature)),
+	} {
+		if _, err := store.Open(key, expires, signature); !errors.Is(err, ErrForbidden) {
+			t.Errorf("%s signature: Open = %v, want a forbidden error", name, err)
+		}
+	}
+
+	// A valid signature does not carry over to another key
+	otherKey, err := store.Save("cam-2", "", []byte("jpeg"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Open(otherKey, expires, signature); !errors.Is(err, ErrForbidden) {
+		t.Errorf("signature of another key: Open = %v, want a forbidden error", err)
+	}
+}
+
+func TestLocalSnapshotStoreOpenRejectsExpiredURL(t *testing.T) {
+	store := newTestLocalStore(t, true, 60)
+
+	key, err := store.Save("cam-1", "", []byte("jpeg"), time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Sign the key as the store would have an hour ago
+	expired := parseExpiredURL(t, store, key, time.Now().Add(-time.Hour))
+	if _, err := store.Open(key, expired.Get("expires"), expired.Get("signature")); !errors.Is(err, ErrForbidden) {
+		t.Errorf("expired URL: Open = %v, want a forbidden error", err)
+	}
+
+	// Moving the expiry forward breaks the signature
+	later := strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)
+	if _, err := store.Open(key, later, expired.Get("signature")); !errors.Is(err, ErrForbidden) {
+		t.Errorf("extended expiry: Open = %v, want a forbidden error", err)
+	}
+}
+
+// parseExpiredURL returns the query of the URL the store builds for key at a past time
+func parseExpiredURL(t *testing.T, store *LocalSnapshotStore, key string, at time.Time) url.Values {
+	t.Helper()
+
+	u, err := url.Parse(store.url(key, at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query()
+}
@@ -0,0 +1,298 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"worker-service/internal/config"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	s3UploadTimeout     = 60 * time.Second
+	s3MaxErrorBody      = 1024
+	s3SigningAlgorithm  = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload   = "UNSIGNED-PAYLOAD"
+	s3TimestampFormat   = "20060102T150405Z"
+	s3DateFormat        = "20060102"
+	s3MaxPresignSeconds = 7 * 24 * 60 * 60
+)
+
+// ----------------------------------------------------------------------
+
+// S3SnapshotStore uploads snapshots and clips to an S3-compatible object store (AWS S3, MinIO, ...).
+// Requests are signed with AWS Signature Version 4; signed snapshot URLs are presigned GET URLs.
+type S3SnapshotStore struct {
+	endpoint        *url.URL
+	region          string
+	bucket          string
+	accessKeyID     string
+	secretAccessKey string
+	pathStyle       bool
+	signed          bool
+	expiry          time.Duration
+	client          *http.Client
+}
+
+// NewS3SnapshotStore creates an S3 snapshot store; without an endpoint the regional AWS endpoint is used
+func NewS3SnapshotStore(cfg *config.Config) (*S3SnapshotStore, error) {
+	endpoint := cfg.S3Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.S3Region)
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", endpoint)
+	}
+
+	// Presigned URLs are valid for at most 7 days; without an expiry they last that long
+	expiry := time.Duration(cfg.SnapshotURLExpirySeconds) * time.Second
+	if expiry <= 0 || expiry > s3MaxPresignSeconds*time.Second {
+		expiry = s3MaxPresignSeconds * time.Second
+	}
+
+	return &S3SnapshotStore{
+		endpoint:        endpointURL,
+		region:          cfg.S3Region,
+		bucket:          cfg.S3Bucket,
+		accessKeyID:     cfg.S3AccessKeyID,
+		secretAccessKey: cfg.S3SecretAccessKey,
+		pathStyle:       cfg.S3ForcePathStyle,
+		signed:          cfg.SnapshotSignedURLs,
+		expiry:          expiry,
+		client:          &http.Client{Timeout: s3UploadTimeout},
+	}, nil
+}
+
+// ----------------------------------------------------------------------
+
+// Save uploads a JPEG snapshot and returns its object key
+func (s *S3SnapshotStore) Save(cameraID, variant string, image []byte, capturedAt time.Time) (string, error) {
+	return s.put(snapshotObjectKey(cameraID, variant, snapshotExt, capturedAt), "image/jpeg", bytes.NewReader(image))
+}
+
+// SaveClip uploads an MP4 event clip and returns its object key
+func (s *S3SnapshotStore) SaveClip(cameraID, path string, capturedAt time.Time) (string, error) {
+	clip, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open clip: %w", err)
+	}
+	defer clip.Close()
+
+	return s.put(snapshotObjectKey(cameraID, clipVariant, clipExt, capturedAt), "video/mp4", clip)
+}
+
+// put uploads an object; the content is read once to hash it for the signature, then sent
+func (s *S3SnapshotStore) put(key, contentType string, content io.ReadSeeker) (string, error) {
+	objectURL := s.objectURL(key)
+
+	payloadHash := sha256.New()
+	size, err := io.Copy(payloadHash, content)
+	if err != nil {
+		return "", fmt.Errorf("failed to read S3 object: %w", err)
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to read S3 object: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, objectURL.String(), io.NopCloser(content))
+	if err != nil {
+		return "", fmt.Errorf("failed to create S3 request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	s.signRequest(req, hex.EncodeToString(payloadHash.Sum(nil)), time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("S3 upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, s3MaxErrorBody))
+		return "", fmt.Errorf("S3 upload failed: %w", &utils.HTTPStatusError{StatusCode: resp.StatusCode, Body: string(body)})
+	}
+
+	return key, nil
+}
+
+// URL returns the URL of an object, presigned when signed URLs are enabled
+func (s *S3SnapshotStore) URL(key string) (string, error) {
+	objectURL := s.objectURL(key)
+	if !s.signed {
+		return objectURL.String(), nil
+	}
+	return s.presign(objectURL, time.Now()), nil
+}
+
+// objectURL returns the path-style or virtual-hosted-style URL of an object
+func (s *S3SnapshotStore) objectURL(key string) *url.URL {
+	objectURL := *s.endpoint
+	basePath := strings.TrimRight(objectURL.Path, "/")
+
+	if s.pathStyle {
+		objectURL.Path = basePath + "/" + s.bucket + "/" + key
+	} else {
+		objectURL.Host = s.bucket + "." + objectURL.Host
+		objectURL.Path = basePath + "/" + key
+	}
+	objectURL.RawPath = ""
+	return &objectURL
+}
+
+// ----------------------------------------------------------------------
+
+// signRequest adds the SigV4 Authorization header, signing host and every header already set
+func (s *S3SnapshotStore) signRequest(req *http.Request, payloadHash string, now time.Time) {
+	timestamp := now.UTC().Format(s3TimestampFormat)
+	req.Header.Set("X-Amz-Date", timestamp)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	canonicalRequest, signedHeaders := s3CanonicalRequest(req, payloadHash)
+
+	scope := s.scope(now)
+	signature := s.signature(now, timestamp, scope, canonicalRequest)
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, s.accessKeyID, scope, signedHeaders, signature))
+}
+
+// s3CanonicalRequest returns the SigV4 canonical request of a request signing host and every header set,
+// and the list of signed headers
+func s3CanonicalRequest(req *http.Request, payloadHash string) (string, string) {
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3CanonicalURI(req.URL),
+		s3CanonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	return canonicalRequest, signedHeaders
+}
+
+// presign returns a GET URL for the object authorized by query parameters until the expiry
+func (s *S3SnapshotStore) presign(objectURL *url.URL, now time.Time) string {
+	timestamp := now.UTC().Format(s3TimestampFormat)
+	scope := s.scope(now)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3SigningAlgorithm)
+	query.Set("X-Amz-Credential", s.accessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", timestamp)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(s.expiry/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		s3CanonicalURI(objectURL),
+		s3CanonicalQuery(query),
+		"host:" + objectURL.Host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+
+	presigned := *objectURL
+	presigned.RawQuery = s3CanonicalQuery(query) + "&X-Amz-Signature=" + s.signature(now, timestamp, scope, canonicalRequest)
+	return presigned.String()
+}
+
+func (s *S3SnapshotStore) scope(now time.Time) string {
+	return now.UTC().Format(s3DateFormat) + "/" + s.region + "/s3/aws4_request"
+}
+
+// signature derives the day's signing key and signs the canonical request
+func (s *S3SnapshotStore) signature(now time.Time, timestamp, scope, canonicalRequest string) string {
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3SigningAlgorithm,
+		timestamp,
+		scope,
+		hex.EncodeToString(canonicalHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretAccessKey), now.UTC().Format(s3DateFormat))
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalURI encodes every path segment as SigV4 requires, keeping the slashes
+func s3CanonicalURI(u *url.URL) string {
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		segments[i] = s3Escape(segment)
+	}
+
+	uri := strings.Join(segments, "/")
+	if uri == "" {
+		return "/"
+	}
+	return uri
+}
+
+// s3CanonicalQuery encodes and sorts query parameters as SigV4 requires
+func s3CanonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// s3Escape percent-encodes everything except the RFC 3986 unreserved characters
+func s3Escape(value string) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			escaped.WriteByte(b)
+		} else {
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
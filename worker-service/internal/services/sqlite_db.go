@@ -0,0 +1,92 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// ----------------------------------------------------------------------
+
+// openSQLite opens (or creates) a SQLite database in WAL mode and applies its schema
+func openSQLite(path, schema string) (*sql.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_pragma=synchronous(NORMAL)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	// SQLite allows a single writer; one connection avoids lock contention between writers and queries
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize database schema: %w", err)
+	}
+
+	return db, nil
+}
+
+// addSQLiteColumns adds the columns of a table that an older schema lacks; each entry is a column definition
+func addSQLiteColumns(db *sql.DB, table string, columns []string) error {
+	existing, err := sqliteColumns(db, table)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		if existing[strings.Fields(column)[0]] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", table, column)); err != nil {
+			return fmt.Errorf("failed to add column to %s: %w", table, err)
+		}
+	}
+
+	return nil
+}
+
+// sqliteColumns returns the names of the columns of a table
+func sqliteColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// renameSQLiteColumn renames a column an older schema still has under its former name and reports whether it did
+func renameSQLiteColumn(db *sql.DB, table, from, to string) (bool, error) {
+	existing, err := sqliteColumns(db, table)
+	if err != nil {
+		return false, err
+	}
+	if !existing[from] || existing[to] {
+		return false, nil
+	}
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", table, from, to)); err != nil {
+		return false, fmt.Errorf("failed to rename column of %s: %w", table, err)
+	}
+	return true, nil
+}
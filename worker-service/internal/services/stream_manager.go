@@ -24,6 +24,11 @@ const (
 	stopTimeout          = 5 * time.Second
 	defaultBytesPerPixel = 3 // BGR24
 
+	// Frame geometry used when probing fails and the caller allows guessing
+	fallbackFrameWidth  = 640
+	fallbackFrameHeight = 480
+	fallbackFrameFPS    = 15
+
 	// Exponential backoff configuration
 	baseRetryDelay = 1 * time.Second
 	maxRetryDelay  = 60 * time.Second // Cap at 1 minute
@@ -40,6 +45,10 @@ type StreamManager struct {
 	mediamtxClient         *MediaMTXClient
 	faceDetectionModelPath string
 	alertService           *AlertService
+	detectionHub           *DetectionHub
+	recordingManager       *RecordingManager
+	eventStore             *EventStore
+	overlayFonts           *OverlayFonts
 }
 
 type StartStreamResponse struct {
@@ -52,7 +61,7 @@ type StartStreamResponse struct {
 	RTMPUrl      string `json:"rtmpUrl"`
 }
 
-func NewStreamManager(cfg *config.Config, mediamtxClient *MediaMTXClient, alertService *AlertService) *StreamManager {
+func NewStreamManager(cfg *config.Config, mediamtxClient *MediaMTXClient, alertService *AlertService, recordingManager *RecordingManager, eventStore *EventStore) *StreamManager {
 	// Seed random for jitter
 	rand.New(rand.NewSource(time.Now().UnixNano()))
 
@@ -63,6 +72,10 @@ func NewStreamManager(cfg *config.Config, mediamtxClient *MediaMTXClient, alertS
 		mediamtxClient:         mediamtxClient,
 		faceDetectionModelPath: findFaceDetectionModel(),
 		alertService:           alertService,
+		detectionHub:           NewDetectionHub(),
+		recordingManager:       recordingManager,
+		eventStore:             eventStore,
+		overlayFonts:           LoadOverlayFonts(append([]string{cfg.OverlayFontPath}, cfg.OverlayFallbackFontPaths...)),
 	}
 
 	if sm.faceDetectionModelPath == "" {
@@ -210,8 +223,11 @@ func (sm *StreamManager) createStreamSession(req *models.StartStreamRequest) (*S
 	// Probe stream info from RTSP URL
 	width, height, maxFPS, err := sm.probeStreamInfo(req.RTSPUrl)
 	if err != nil {
-		utils.GetLogger().Warnf("Failed to probe stream %s, using defaults: %v", req.RTSPUrl, err)
-		width, height, maxFPS = 640, 480, 15 // Safe defaults
+		if !req.AllowFallbackDimensions {
+			return nil, err
+		}
+		utils.GetLogger().Warnf("Failed to probe stream %s, using fallback dimensions: %v", redactURL(req.RTSPUrl), err)
+		width, height, maxFPS = fallbackFrameWidth, fallbackFrameHeight, fallbackFrameFPS
 	}
 
 	faceDetectionEnabled := req.FaceDetectionEnabled && len(sm.faceDetectionModelPath) > 0
@@ -239,11 +255,21 @@ func (sm *StreamManager) createStreamSession(req *models.StartStreamRequest) (*S
 		targetFPS:      maxFPS,
 
 		faceDetector:         NewFaceDetectionEngine(req.CameraID, sm.faceDetectionModelPath),
-		overlay:              NewOverlayRenderer(req.CameraID),
+		overlay:              NewOverlayRenderer(req.CameraID, sm.overlayFonts, sm.config.OverlayLogoDir),
+		zones:                NewZoneFilter(),
+		privacyMasks:         NewPrivacyMasker(),
+		tuning:               NewDetectionTuning(),
+		tracker:              NewFaceTracker(),
+		anonymizer:           NewFaceAnonymizer(sm.config.AllowUnredactedAlerts),
+		latestFrame:          NewLatestFrameBuffer(width, height),
+		detectionHub:         sm.detectionHub,
+		eventStore:           sm.eventStore,
 		faceDetectionEnabled: faceDetectionEnabled,
 		alertService:         sm.alertService,
-		alertCooldown:        5 * time.Second,
-		lastAlertTime:        time.Time{},
+		alertRules:           NewAlertRuleEngine(),
+		alertSinks:           NewCameraAlertSinks(),
+		incidents:            NewIncidentTracker(time.Duration(sm.config.AlertIncidentQuietSeconds) * time.Second),
+		annotatedSnapshots:   sm.config.AlertAnnotatedSnapshots,
 
 		// Initialize frame metrics
 		totalFramesReceived:  0,
@@ -252,6 +278,86 @@ func (sm *StreamManager) createStreamSession(req *models.StartStreamRequest) (*S
 		lastMetricsLog:       time.Now(),
 	}
 
+	// Initialize PTZ control if the camera exposes ONVIF
+	if req.ONVIF != nil {
+		session.ptzClient = NewONVIFPTZClient(req.CameraID, onvifConfigWithRTSPCredentials(req.ONVIF, req.RTSPUrl))
+		session.alertPresetToken = req.ONVIF.AlertPresetToken
+	}
+
+	// Apply the camera's overlay configuration, if provided
+	if req.Overlay != nil {
+		if err := session.overlay.UpdateConfig(req.Overlay); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the camera's detection settings, if provided
+	if req.DetectionSettings != nil {
+		if err := applyDetectionSettings(session, req.DetectionSettings); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the camera's alert rules, if provided
+	if req.AlertRules != nil {
+		if err := session.alertRules.SetRules(*req.AlertRules); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the camera's alert sinks, if provided
+	if len(req.AlertSinks) > 0 {
+		if err := session.alertSinks.SetSinks(req.AlertSinks); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the camera's detection zones, if provided
+	if len(req.Zones) > 0 {
+		if err := session.zones.SetZones(req.Zones); err != nil {
+			return nil, err
+		}
+		session.overlay.SetZones(session.zones.Zones())
+	}
+
+	// Apply the camera's privacy masks, if provided
+	if len(req.PrivacyMasks) > 0 {
+		if err := session.privacyMasks.SetMasks(req.PrivacyMasks); err != nil {
+			return nil, err
+		}
+	}
+
+	// Apply the camera's face anonymization, if provided
+	if req.Anonymization != nil {
+		if err := session.anonymizer.SetOptions(*req.Anonymization); err != nil {
+			return nil, err
+		}
+	}
+
+	// Initialize the event clip buffer if enabled
+	if sm.config.AlertClipEnabled {
+		clipBuffer, err := NewClipBuffer(req.CameraID, sm.config.AlertClipBufferDir,
+			time.Duration(sm.config.AlertClipPreSeconds)*time.Second,
+			time.Duration(sm.config.AlertClipPostSeconds)*time.Second)
+		if err != nil {
+			utils.GetLogger().Warnf("Alert clips disabled for camera %s: %v", req.CameraID, err)
+		} else {
+			session.clipBuffer = clipBuffer
+		}
+	}
+
+	// Initialize continuous recording if requested
+	if req.Recording != nil && req.Recording.Enabled {
+		if err := sm.recordingManager.PrepareCamera(req.CameraID); err != nil {
+			utils.GetLogger().Warnf("Recording disabled for camera %s: %v", req.CameraID, err)
+		} else {
+			session.recordingSource = req.Recording.Source
+			if session.recordingSource == "" {
+				session.recordingSource = models.RecordingSourceProcessed
+			}
+		}
+	}
+
 	// Initialize face detection if available
 	if session.faceDetectionEnabled {
 		if err := session.faceDetector.Initialize(); err != nil {
@@ -260,6 +366,8 @@ func (sm *StreamManager) createStreamSession(req *models.StartStreamRequest) (*S
 		}
 	}
 
+	session.incidentReporter = NewIncidentReporter(session)
+
 	return session, nil
 }
 
@@ -281,6 +389,7 @@ func (sm *StreamManager) buildStreamResponse(req *models.StartStreamRequest, ses
 func (sm *StreamManager) cleanupFailedSession(session *StreamSession) {
 	utils.GetLogger().Warnf("Cleaning up failed session for camera %s", session.CameraID)
 	session.Cleanup()
+	session.incidentReporter.Close()
 
 	// Signal stop
 	select {
@@ -289,4 +398,5 @@ func (sm *StreamManager) cleanupFailedSession(session *StreamSession) {
 	}
 
 	sm.unregisterSession(session.CameraID)
+	sm.detectionHub.CloseCamera(session.CameraID)
 }
@@ -2,7 +2,7 @@ package services
 
 // ----------------------------------------------------------------------
 import (
-	"fmt"
+	"strings"
 	"time"
 	"worker-service/internal/models"
 	"worker-service/internal/utils"
@@ -11,6 +11,16 @@ import (
 // ----------------------------------------------------------------------
 
 func (sm *StreamManager) StartStream(req *models.StartStreamRequest) (*StartStreamResponse, error) {
+	if err := validateCameraID(req.CameraID); err != nil {
+		return nil, err
+	}
+
+	if req.Recording != nil && req.Recording.Enabled {
+		if err := validateRecordingSource(req.Recording.Source, len(req.PrivacyMasks)); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := sm.validateStreamStart(req.CameraID); err != nil {
 		return nil, err
 	}
@@ -25,7 +35,7 @@ func (sm *StreamManager) StartStream(req *models.StartStreamRequest) (*StartStre
 
 	if err := sm.verifyStreamIsLive(session.CameraID, 15, 2*time.Second); err != nil {
 		sm.cleanupFailedSession(session)
-		return nil, fmt.Errorf("stream failed to start: %v", err)
+		return nil, upstreamUnavailableError(err, "stream failed to start")
 	}
 
 	return sm.buildStreamResponse(req, session), nil
@@ -52,11 +62,35 @@ func (sm *StreamManager) StopStream(cameraID string) (*models.StopStreamResponse
 	}
 
 	sm.unregisterSession(cameraID)
+	sm.detectionHub.CloseCamera(cameraID)
+
+	// Reported in the background, after the events of the incident still waiting for their clip
+	session.closeIncident(time.Now())
+	session.incidentReporter.Close()
+
 	utils.GetLogger().Infof("Stream stopped for camera %s", cameraID)
 
 	return &models.StopStreamResponse{CameraID: cameraID}, nil
 }
 
+// validateCameraID rejects camera IDs that cannot be used as a path element; the camera ID
+// names the clip buffer and recording directories
+func validateCameraID(cameraID string) error {
+	if cameraID == "" || cameraID == "." || cameraID == ".." || strings.ContainsAny(cameraID, `/\`) {
+		return invalidParameterError("invalid camera ID %q", cameraID)
+	}
+	return nil
+}
+
+// validateRecordingSource rejects recording the raw stream of a camera with privacy masks, which
+// would store the masked areas unredacted
+func validateRecordingSource(source string, maskCount int) error {
+	if source == models.RecordingSourceRaw && maskCount > 0 {
+		return invalidParameterError("the raw stream cannot be recorded while privacy masks are set; record the processed stream instead")
+	}
+	return nil
+}
+
 // validateStreamStart checks if a new stream can be started
 func (sm *StreamManager) validateStreamStart(cameraID string) error {
 	sm.sessionsMutex.RLock()
@@ -66,12 +100,17 @@ func (sm *StreamManager) validateStreamStart(cameraID string) error {
 
 	// Check if stream already exists for this camera
 	if _, exists := sm.sessions[cameraID]; exists {
-		return fmt.Errorf("stream already active for camera %s", cameraID)
+		return alreadyActiveError("stream already active for camera %s", cameraID)
 	}
 
 	// Get current active stream count
 	currentStreams := len(sm.sessions)
 
+	// Refuse to start beyond the hard limit, if one is configured
+	if sm.config.MaxStreamCapacity > 0 && currentStreams >= sm.config.MaxStreamCapacity {
+		return capacityExceededError("stream capacity exceeded: %d/%d streams active", currentStreams, sm.config.MaxStreamCapacity)
+	}
+
 	// warning about capacity
 	if currentStreams >= sm.OptimalStreamCapacity {
 		// Calculate how far over optimal capacity
@@ -120,7 +159,7 @@ func (sm *StreamManager) getSession(cameraID string) (*StreamSession, error) {
 
 	session, exists := sm.sessions[cameraID]
 	if !exists {
-		return nil, fmt.Errorf("stream not found for camera %s", cameraID)
+		return nil, notFoundError("stream not found for camera %s", cameraID)
 	}
 	return session, nil
 }
@@ -0,0 +1,301 @@
+package services
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+	"worker-service/internal/models"
+	"worker-service/internal/utils"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	defaultProbeTimeout = 30 * time.Second
+)
+
+// supportedVideoCodecs lists the codecs the input FFmpeg pipeline is known to decode
+var supportedVideoCodecs = map[string]bool{
+	"h264":  true,
+	"hevc":  true,
+	"mjpeg": true,
+	"mpeg4": true,
+}
+
+// ----------------------------------------------------------------------
+
+// ProbeError describes a classified stream probe failure
+type ProbeError struct {
+	Kind    models.ProbeFailureKind
+	Message string
+}
+
+func (e *ProbeError) Error() string {
+	return fmt.Sprintf("stream probe failed (%s): %s", e.Kind, e.Message)
+}
+
+// ErrorCode reports unusable streams as invalid parameters and everything else as an upstream failure
+func (e *ProbeError) ErrorCode() utils.ErrorCode {
+	switch e.Kind {
+	case models.ProbeFailureUnsupportedCodec, models.ProbeFailureNoVideoStream:
+		return utils.ErrorCodeInvalidParameter
+	}
+	return utils.ErrorCodeUpstreamUnavailable
+}
+
+// ffprobeOutput mirrors the parts of ffprobe's JSON output used by the worker
+type ffprobeOutput struct {
+	Streams []struct {
+		Index         int    `json:"index"`
+		CodecType     string `json:"codec_type"`
+		CodecName     string `json:"codec_name"`
+		Profile       string `json:"profile"`
+		Width         int    `json:"width"`
+		Height        int    `json:"height"`
+		RFrameRate    string `json:"r_frame_rate"`
+		AvgFrameRate  string `json:"avg_frame_rate"`
+		BitRate       string `json:"bit_rate"`
+		SampleRate    string `json:"sample_rate"`
+		Channels      int    `json:"channels"`
+		ChannelLayout string `json:"channel_layout"`
+	} `json:"streams"`
+	Format struct {
+		BitRate string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+// ----------------------------------------------------------------------
+
+// ProbeStream runs full diagnostics against a stream URL, including latency to the first decoded frame
+func (sm *StreamManager) ProbeStream(req *models.ProbeStreamRequest) (*models.ProbeStreamResponse, error) {
+	timeout := defaultProbeTimeout
+	if req.TimeoutMs > 0 {
+		timeout = time.Duration(req.TimeoutMs) * time.Millisecond
+	}
+
+	// Both steps share one deadline, so the probe never takes longer than the requested timeout
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result, err := probeStream(ctx, req.RTSPUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	latency, err := measureFirstFrameLatency(ctx, req.RTSPUrl)
+	if err != nil {
+		return nil, err
+	}
+	result.LatencyToFirstFrameMs = latency.Milliseconds()
+
+	return result, nil
+}
+
+// probeStreamInfo returns the frame geometry the processing pipeline needs for a stream
+func (sm *StreamManager) probeStreamInfo(rtspUrl string) (width, height, fps int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultProbeTimeout)
+	defer cancel()
+
+	result, err := probeStream(ctx, rtspUrl)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	fps = int(result.FPS)
+	if fps <= 0 || fps > 120 {
+		utils.GetLogger().Warnf("Invalid FPS detected (%.2f), using default 15", result.FPS)
+		fps = 15
+	}
+
+	return result.Width, result.Height, fps, nil
+}
+
+// ----------------------------------------------------------------------
+
+// probeStream runs ffprobe against the stream and classifies any failure
+func probeStream(ctx context.Context, rtspUrl string) (*models.ProbeStreamResponse, error) {
+	logger := utils.GetLogger()
+	logger.Infof("Probing stream info for: %s", redactURL(rtspUrl))
+
+	cmd := exec.CommandContext(ctx, "ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_streams",
+		"-show_format",
+		"-rtsp_transport", "tcp", // Force TCP transport
+		"-analyzeduration", "10M",
+		"-probesize", "10M",
+		rtspUrl,
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	startTime := time.Now()
+	output, err := cmd.Output()
+	probeDuration := time.Since(startTime)
+
+	if err != nil {
+		probeErr := classifyProbeFailure(ctx, err, stderr.String())
+		logger.Warnf("Failed to probe stream %s: %v", redactURL(rtspUrl), probeErr)
+		return nil, probeErr
+	}
+
+	var parsed ffprobeOutput
+	if err := json.Unmarshal(output, &parsed); err != nil {
+		return nil, &ProbeError{Kind: models.ProbeFailureUnknown, Message: fmt.Sprintf("failed to parse ffprobe output: %v", err)}
+	}
+
+	result := &models.ProbeStreamResponse{
+		AudioTracks:     []models.AudioTrackInfo{},
+		ProbeDurationMs: probeDuration.Milliseconds(),
+	}
+
+	videoFound := false
+	for _, stream := range parsed.Streams {
+		switch stream.CodecType {
+		case "video":
+			if videoFound {
+				continue
+			}
+			videoFound = true
+
+			result.Codec = stream.CodecName
+			result.Profile = stream.Profile
+			result.Width = stream.Width
+			result.Height = stream.Height
+
+			// Try avg_frame_rate first (more reliable), fall back to r_frame_rate
+			result.FPS = parseFrameRate(stream.AvgFrameRate)
+			if result.FPS <= 0 {
+				result.FPS = parseFrameRate(stream.RFrameRate)
+			}
+
+			if bitrate, err := strconv.Atoi(stream.BitRate); err == nil {
+				result.BitrateKbps = bitrate / 1000
+			}
+
+		case "audio":
+			sampleRate, _ := strconv.Atoi(stream.SampleRate)
+			result.AudioTracks = append(result.AudioTracks, models.AudioTrackInfo{
+				Index:         stream.Index,
+				Codec:         stream.CodecName,
+				SampleRate:    sampleRate,
+				Channels:      stream.Channels,
+				ChannelLayout: stream.ChannelLayout,
+			})
+		}
+	}
+
+	if !videoFound {
+		return nil, &ProbeError{Kind: models.ProbeFailureNoVideoStream, Message: "stream contains no video track"}
+	}
+
+	if !supportedVideoCodecs[result.Codec] {
+		return nil, &ProbeError{Kind: models.ProbeFailureUnsupportedCodec, Message: fmt.Sprintf("video codec %q is not supported", result.Codec)}
+	}
+
+	if result.Width <= 0 || result.Height <= 0 {
+		return nil, &ProbeError{Kind: models.ProbeFailureUnknown, Message: "stream reported no frame dimensions"}
+	}
+
+	// Fall back to the container bitrate when the stream does not report one
+	if result.BitrateKbps == 0 {
+		if bitrate, err := strconv.Atoi(parsed.Format.BitRate); err == nil {
+			result.BitrateKbps = bitrate / 1000
+		}
+	}
+
+	logger.Infof("Stream probed: %s %dx%d @ %.2ffps", result.Codec, result.Width, result.Height, result.FPS)
+	return result, nil
+}
+
+// measureFirstFrameLatency times how long FFmpeg takes to connect and decode one video frame
+func measureFirstFrameLatency(ctx context.Context, rtspUrl string) (time.Duration, error) {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-v", "error",
+		"-rtsp_transport", "tcp",
+		"-i", rtspUrl,
+		"-frames:v", "1",
+		"-an",
+		"-f", "null",
+		"-",
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	startTime := time.Now()
+	if err := cmd.Run(); err != nil {
+		return 0, classifyProbeFailure(ctx, err, stderr.String())
+	}
+
+	return time.Since(startTime), nil
+}
+
+// classifyProbeFailure maps FFmpeg/ffprobe errors to a probe failure kind
+func classifyProbeFailure(ctx context.Context, err error, stderr string) *ProbeError {
+	message := strings.TrimSpace(stderr)
+	if message == "" {
+		message = err.Error()
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return &ProbeError{Kind: models.ProbeFailureTimeout, Message: "timed out waiting for stream"}
+	}
+
+	lower := strings.ToLower(stderr)
+	switch {
+	case strings.Contains(lower, "name or service not known"),
+		strings.Contains(lower, "temporary failure in name resolution"),
+		strings.Contains(lower, "no address associated with hostname"),
+		strings.Contains(lower, "nodename nor servname"):
+		return &ProbeError{Kind: models.ProbeFailureDNS, Message: message}
+	case strings.Contains(lower, "connection refused"):
+		return &ProbeError{Kind: models.ProbeFailureConnectionRefused, Message: message}
+	case strings.Contains(lower, "401 unauthorized"), strings.Contains(lower, "401 authorization"):
+		return &ProbeError{Kind: models.ProbeFailureUnauthorized, Message: message}
+	case strings.Contains(lower, "timed out"), strings.Contains(lower, "timeout"):
+		return &ProbeError{Kind: models.ProbeFailureTimeout, Message: message}
+	case strings.Contains(lower, "decoder not found"),
+		strings.Contains(lower, "unsupported codec"),
+		strings.Contains(lower, "could not find codec parameters"):
+		return &ProbeError{Kind: models.ProbeFailureUnsupportedCodec, Message: message}
+	}
+
+	return &ProbeError{Kind: models.ProbeFailureUnknown, Message: message}
+}
+
+// parseFrameRate converts an ffprobe rational ("30000/1001") to frames per second
+func parseFrameRate(rate string) float64 {
+	parts := strings.Split(strings.TrimSpace(rate), "/")
+	if len(parts) != 2 {
+		return 0
+	}
+
+	num, err1 := strconv.ParseFloat(parts[0], 64)
+	den, err2 := strconv.ParseFloat(parts[1], 64)
+	if err1 != nil || err2 != nil || den <= 0 {
+		return 0
+	}
+
+	return num / den
+}
+
+// redactURL strips credentials from a stream URL before it is logged
+func redactURL(rawURL string) string {
+	at := strings.LastIndex(rawURL, "@")
+	scheme := strings.Index(rawURL, "://")
+	if at == -1 || scheme == -1 || at < scheme {
+		return rawURL
+	}
+	return rawURL[:scheme+3] + "***@" + rawURL[at+1:]
+}
@@ -28,6 +28,16 @@ type StreamSession struct {
 	outputFFmpeg *FFmpegProcess
 	faceDetector *FaceDetectionEngine
 	overlay      *OverlayRenderer
+	zones        *ZoneFilter
+	privacyMasks *PrivacyMasker
+	tuning       *DetectionTuning
+	tracker      *FaceTracker
+	anonymizer   *FaceAnonymizer
+	ptzClient    *ONVIFPTZClient
+	latestFrame  *LatestFrameBuffer
+	detectionHub *DetectionHub
+	clipBuffer   *ClipBuffer
+	eventStore   *EventStore
 
 	// Configuration
 	faceDetectionEnabled bool
@@ -37,15 +47,32 @@ type StreamSession struct {
 	targetFPS            int
 
 	// Alert service
-	alertService  *AlertService
-	lastAlertTime time.Time
-	alertCooldown time.Duration
+	alertService *AlertService
+	alertRules   *AlertRuleEngine
+	alertSinks   *CameraAlertSinks
+	incidents    *IncidentTracker
+
+	// Reports incident transitions in order; removes the clip buffer once stopped
+	incidentReporter *IncidentReporter
+
+	// Whether alerts carry an annotated snapshot besides the clean one
+	annotatedSnapshots bool
+
+	// PTZ preset recalled when an alert fires (empty to disable)
+	alertPresetToken string
+
+	// Recording source ("processed" or "raw", empty when not recording)
+	recordingSource string
 
 	// Frame metrics (atomic for thread-safe updates)
 	totalFramesReceived  int64
 	totalFramesProcessed int64
 	totalFramesDropped   int64
+	frameSequence        int64
 	lastMetricsLog       time.Time
+
+	// Connected MJPEG preview clients (atomic)
+	mjpegClients int32
 }
 
 // ----------------------------------------------------------------------
@@ -90,3 +117,13 @@ func (s *StreamSession) Cleanup() {
 		s.outputFFmpeg.Close()
 	}
 }
+
+// closeIncident reports the open incident, if any, as closed
+func (s *StreamSession) closeIncident(now time.Time) {
+	if s.incidents == nil || s.incidentReporter == nil {
+		return
+	}
+	if transition := s.incidents.Close(now); transition != nil {
+		s.incidentReporter.Report(transition, now)
+	}
+}
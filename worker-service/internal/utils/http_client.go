@@ -14,9 +14,10 @@ import (
 // ----------------------------------------------------------------------
 
 type HTTPClient struct {
-	baseURL string
-	apiKey  string
-	client  *http.Client
+	baseURL        string
+	apiKey         string
+	signingSecrets []string
+	client         *http.Client
 }
 
 func NewHTTPClient(baseURL, apiKey string) *HTTPClient {
@@ -29,10 +30,35 @@ func NewHTTPClient(baseURL, apiKey string) *HTTPClient {
 	}
 }
 
+// SetSigningSecrets makes POST requests carry an HMAC signature of their body made with every secret
+func (h *HTTPClient) SetSigningSecrets(secrets []string) {
+	h.signingSecrets = secrets
+}
+
+// HTTPStatusError is returned when the backend answers with a non-2xx status
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("API error: %d - %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether the same request may succeed if sent again later
+func (e *HTTPStatusError) Retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+}
+
 // ----------------------------------------------------------------------
 
 // Post sends POST request to backend
 func (h *HTTPClient) Post(endpoint string, body interface{}) (map[string]interface{}, error) {
+	return h.PostWithHeaders(endpoint, body, nil)
+}
+
+// PostWithHeaders sends POST request to backend with additional headers
+func (h *HTTPClient) PostWithHeaders(endpoint string, body interface{}, headers map[string]string) (map[string]interface{}, error) {
 	jsonBody, err := json.Marshal(body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -47,6 +73,12 @@ func (h *HTTPClient) Post(endpoint string, body interface{}) (map[string]interfa
 	if h.apiKey != "" {
 		req.Header.Set("X-Backend-Worker-API-Key", h.apiKey)
 	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	if len(h.signingSecrets) > 0 {
+		req.Header.Set(SignatureHeader, SignPayload(jsonBody, time.Now(), h.signingSecrets))
+	}
 
 	resp, err := h.client.Do(req)
 	if err != nil {
@@ -60,7 +92,7 @@ func (h *HTTPClient) Post(endpoint string, body interface{}) (map[string]interfa
 	}
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("API error: %d - %s", resp.StatusCode, string(body_bytes))
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Body: string(body_bytes)}
 	}
 
 	var result map[string]interface{}
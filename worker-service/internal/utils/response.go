@@ -3,6 +3,7 @@ package utils
 // ----------------------------------------------------------------------
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +21,8 @@ const (
 	StatusNotFound     = http.StatusNotFound            // 404
 	StatusConflict     = http.StatusConflict            // 409
 	StatusServerError  = http.StatusInternalServerError // 500
+	StatusBadGateway   = http.StatusBadGateway          // 502
+	StatusUnavailable  = http.StatusServiceUnavailable  // 503
 )
 
 // Response messages
@@ -32,8 +35,54 @@ const (
 	ResponseServerError     = "Internal Server Error!"
 	ResponseValidationError = "Validation Error!"
 	ResponseResourceExists  = "Resource already exists!"
+	ResponseBadGateway      = "Bad Gateway!"
+	ResponseUnavailable     = "Service Unavailable!"
 )
 
+// ErrorCode is a stable machine-readable error identifier
+type ErrorCode string
+
+// Error codes
+const (
+	ErrorCodeInvalidParameter    ErrorCode = "INVALID_PARAMETER"
+	ErrorCodeUnauthorized        ErrorCode = "UNAUTHORIZED"
+	ErrorCodeForbidden           ErrorCode = "FORBIDDEN"
+	ErrorCodeNotFound            ErrorCode = "NOT_FOUND"
+	ErrorCodeAlreadyActive       ErrorCode = "ALREADY_ACTIVE"
+	ErrorCodeCapacityExceeded    ErrorCode = "CAPACITY_EXCEEDED"
+	ErrorCodeUpstreamUnavailable ErrorCode = "UPSTREAM_UNAVAILABLE"
+	ErrorCodeInternal            ErrorCode = "INTERNAL_ERROR"
+)
+
+// errorCodeStatus maps each error code to its HTTP status and response message
+var errorCodeStatus = map[ErrorCode]StatusInfo{
+	ErrorCodeInvalidParameter:    {ResponseCode: StatusBadRequest, ResponseMessage: ResponseBadRequest},
+	ErrorCodeUnauthorized:        {ResponseCode: StatusUnauthorized, ResponseMessage: ResponseUnauthorized},
+	ErrorCodeForbidden:           {ResponseCode: StatusForbidden, ResponseMessage: ResponseForbidden},
+	ErrorCodeNotFound:            {ResponseCode: StatusNotFound, ResponseMessage: ResponseNotFound},
+	ErrorCodeAlreadyActive:       {ResponseCode: StatusConflict, ResponseMessage: ResponseResourceExists},
+	ErrorCodeCapacityExceeded:    {ResponseCode: StatusUnavailable, ResponseMessage: ResponseUnavailable},
+	ErrorCodeUpstreamUnavailable: {ResponseCode: StatusBadGateway, ResponseMessage: ResponseBadGateway},
+	ErrorCodeInternal:            {ResponseCode: StatusServerError, ResponseMessage: ResponseServerError},
+}
+
+// statusErrorCode is the default error code for errors that do not carry one
+var statusErrorCode = map[int]ErrorCode{
+	StatusBadRequest:   ErrorCodeInvalidParameter,
+	StatusUnauthorized: ErrorCodeUnauthorized,
+	StatusForbidden:    ErrorCodeForbidden,
+	StatusNotFound:     ErrorCodeNotFound,
+	StatusConflict:     ErrorCodeAlreadyActive,
+	StatusBadGateway:   ErrorCodeUpstreamUnavailable,
+	StatusUnavailable:  ErrorCodeCapacityExceeded,
+}
+
+// CodedError is implemented by domain errors that carry a machine-readable code
+type CodedError interface {
+	error
+	ErrorCode() ErrorCode
+}
+
 // ----------------------------------------------------------------------
 
 // StatusInfo represents the status object in responses
@@ -58,8 +107,9 @@ type ErrorResponse struct {
 
 // ErrorDetail contains error details
 type ErrorDetail struct {
-	Message string `json:"message"`
-	Stack   string `json:"stack,omitempty"`
+	Code    ErrorCode `json:"code"`
+	Message string    `json:"message"`
+	Stack   string    `json:"stack,omitempty"`
 }
 
 // ----------------------------------------------------------------------
@@ -76,9 +126,25 @@ func SendSuccessResponse(c *gin.Context, statusCode int, responseMessage string,
 	})
 }
 
-// SendErrorResponse sends a standardized error response
+// SendErrorResponse sends a standardized error response. Errors carrying an
+// error code override the given status with the one mapped to their code.
 func SendErrorResponse(c *gin.Context, statusCode int, responseMessage string, err error) {
+	code, ok := statusErrorCode[statusCode]
+	if !ok {
+		code = ErrorCodeInternal
+	}
+
+	var codedErr CodedError
+	if errors.As(err, &codedErr) {
+		if status, exists := errorCodeStatus[codedErr.ErrorCode()]; exists {
+			code = codedErr.ErrorCode()
+			statusCode = status.ResponseCode
+			responseMessage = status.ResponseMessage
+		}
+	}
+
 	errorDetail := &ErrorDetail{
+		Code:    code,
 		Message: err.Error(),
 	}
 
@@ -122,3 +188,8 @@ func ErrorConflict(c *gin.Context, err error) {
 func ErrorServerError(c *gin.Context, err error) {
 	SendErrorResponse(c, StatusServerError, ResponseServerError, err)
 }
+
+// ErrorFromService sends the status mapped to a service error's code, or 500 for uncoded errors
+func ErrorFromService(c *gin.Context, err error) {
+	SendErrorResponse(c, StatusServerError, ResponseServerError, err)
+}
@@ -0,0 +1,124 @@
+package utils
+
+// ----------------------------------------------------------------------
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ----------------------------------------------------------------------
+
+// SignatureHeader carries the signature of outbound alert requests, in the form
+// "t=<unix timestamp>,v1=<signature>[,v1=<signature>...]" with one signature per active secret.
+// A signature is the hex HMAC-SHA256 of "<timestamp>.<body>".
+const SignatureHeader = "X-VisionGuard-Signature"
+
+// DefaultSignatureTolerance is the maximum age of a signature receivers should accept
+const DefaultSignatureTolerance = 5 * time.Minute
+
+// MinSigningSecretLength is the shortest signing secret accepted
+const MinSigningSecretLength = 16
+
+const signatureScheme = "v1"
+
+// Signature verification errors
+var (
+	ErrSignatureMissing   = errors.New("signature missing")
+	ErrSignatureMalformed = errors.New("signature malformed")
+	ErrSignatureExpired   = errors.New("signature timestamp outside tolerance")
+	ErrSignatureMismatch  = errors.New("no signature matches")
+)
+
+// ----------------------------------------------------------------------
+
+// SignPayload returns the signature header value of a body signed at timestamp with every secret.
+// Signing with all active secrets lets receivers rotate to a new secret without missing requests.
+func SignPayload(body []byte, timestamp time.Time, secrets []string) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+
+	parts := make([]string, 0, len(secrets)+1)
+	parts = append(parts, "t="+unix)
+	for _, secret := range secrets {
+		parts = append(parts, signatureScheme+"="+computeSignature(secret, unix, body))
+	}
+	return strings.Join(parts, ",")
+}
+
+// VerifySignature checks a signature header against the body: it passes when any signature matches any secret.
+// Signatures older or newer than tolerance are rejected; a tolerance of 0 disables the check.
+func VerifySignature(header string, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	if header == "" {
+		return ErrSignatureMissing
+	}
+
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrSignatureMalformed
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case signatureScheme:
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrSignatureMalformed
+	}
+
+	if tolerance > 0 {
+		age := now.Sub(time.Unix(unix, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrSignatureExpired
+		}
+	}
+
+	for _, secret := range secrets {
+		expected := computeSignature(secret, timestamp, body)
+		for _, signature := range signatures {
+			if hmac.Equal([]byte(signature), []byte(expected)) {
+				return nil
+			}
+		}
+	}
+	return ErrSignatureMismatch
+}
+
+// VerifyRequest verifies the signature of an incoming request and returns its body.
+// The request body is restored so handlers can still read it.
+func VerifyRequest(req *http.Request, secrets []string, tolerance time.Duration) ([]byte, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	if err := VerifySignature(req.Header.Get(SignatureHeader), body, secrets, tolerance, time.Now()); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func computeSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
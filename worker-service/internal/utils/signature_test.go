@@ -0,0 +1,82 @@
+package utils
+
+// ----------------------------------------------------------------------
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// ----------------------------------------------------------------------
+
+const (
+	testSecretOld   = "old-secret-0123456789"
+	testSecretNew   = "new-secret-0123456789"
+	testSecretOther = "other-secret-0123456789"
+)
+
+var (
+	testBody      = []byte(`{"event":"opened"}`)
+	testSignedAt  = time.Date(2026, time.October, 16, 12, 0, 0, 0, time.UTC)
+	testTimestamp = strconv.FormatInt(testSignedAt.Unix(), 10)
+)
+
+// ----------------------------------------------------------------------
+
+func TestVerifySignature(t *testing.T) {
+	rotating := SignPayload(testBody, testSignedAt, []string{testSecretOld, testSecretNew})
+	oldOnly := computeSignature(testSecretOld, testTimestamp, testBody)
+	otherOnly := computeSignature(testSecretOther, testTimestamp, testBody)
+
+	for _, tc := range []struct {
+		name      string
+		header    string
+		body      []byte
+		secrets   []string
+		tolerance time.Duration
+		now       time.Time
+		want      error
+	}{
+		// Rotation: a header signed with every active secret passes with either of them
+		{"rotation, receiver on the old secret", rotating, testBody, []string{testSecretOld}, time.Minute, testSignedAt, nil},
+		{"rotation, receiver on the new secret", rotating, testBody, []string{testSecretNew}, time.Minute, testSignedAt, nil},
+		{"rotation, receiver with both secrets", rotating, testBody, []string{testSecretOther, testSecretNew}, time.Minute, testSignedAt, nil},
+		{"rotation, matching signature listed last", "t=" + testTimestamp + ",v1=" + otherOnly + ",v1=" + oldOnly, testBody, []string{testSecretOld}, time.Minute, testSignedAt, nil},
+		{"unknown fields are ignored", "t=" + testTimestamp + ",v0=legacy,v1=" + oldOnly, testBody, []string{testSecretOld}, time.Minute, testSignedAt, nil},
+
+		// Tolerance
+		{"within tolerance", rotating, testBody, []string{testSecretNew}, time.Minute, testSignedAt.Add(59 * time.Second), nil},
+		{"too old", rotating, testBody, []string{testSecretNew}, time.Minute, testSignedAt.Add(61 * time.Second), ErrSignatureExpired},
+		{"too far in the future", rotating, testBody, []string{testSecretNew}, time.Minute, testSignedAt.Add(-61 * time.Second), ErrSignatureExpired},
+		{"tolerance disabled", rotating, testBody, []string{testSecretNew}, 0, testSignedAt.Add(24 * time.Hour), nil},
+
+		// Malformed headers
+		{"missing", "", testBody, []string{testSecretNew}, time.Minute, testSignedAt, ErrSignatureMissing},
+		{"no key-value pairs", "garbage", testBody, []string{testSecretNew}, time.Minute, testSignedAt, ErrSignatureMalformed},
+		{"no timestamp", "v1=" + oldOnly, testBody, []string{testSecretOld}, time.Minute, testSignedAt, ErrSignatureMalformed},
+		{"invalid timestamp", "t=noon,v1=" + oldOnly, testBody, []string{testSecretOld}, time.Minute, testSignedAt, ErrSignatureMalformed},
+		{"no signature", "t=" + testTimestamp, testBody, []string{testSecretOld}, time.Minute, testSignedAt, ErrSignatureMalformed},
+
+		// Mismatches
+		{"unknown secret", rotating, testBody, []string{testSecretOther}, time.Minute, testSignedAt, ErrSignatureMismatch},
+		{"tampered body", rotating, []byte(`{"event":"closed"}`), []string{testSecretNew}, time.Minute, testSignedAt, ErrSignatureMismatch},
+		{"tampered timestamp", "t=" + strconv.FormatInt(testSignedAt.Unix()+1, 10) + ",v1=" + oldOnly, testBody, []string{testSecretOld}, time.Minute, testSignedAt, ErrSignatureMismatch},
+		{"no secrets", rotating, testBody, nil, time.Minute, testSignedAt, ErrSignatureMismatch},
+	} {
+		if err := VerifySignature(tc.header, tc.body, tc.secrets, tc.tolerance, tc.now); !errors.Is(err, tc.want) {
+			t.Errorf("%s: VerifySignature = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestSignPayloadSignsWithEverySecret(t *testing.T) {
+	want := "t=" + testTimestamp +
+		",v1=" + computeSignature(testSecretOld, testTimestamp, testBody) +
+		",v1=" + computeSignature(testSecretNew, testTimestamp, testBody)
+
+	if got := SignPayload(testBody, testSignedAt, []string{testSecretOld, testSecretNew}); got != want {
+		t.Errorf("SignPayload = %s, want %s", got, want)
+	}
+}